@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"unicode/utf16"
+)
+
+// fileEncodings maps the --to-encoding names to their canonical form.
+var fileEncodings = map[string]string{
+	"utf-8": "utf-8", "utf8": "utf-8",
+	"utf-16le": "utf-16le", "utf16le": "utf-16le",
+	"utf-16be": "utf-16be", "utf16be": "utf-16be",
+	"latin1": "latin1", "iso-8859-1": "latin1",
+}
+
+// encodeUTF16BE converts s to UTF-16BE with a byte order mark.
+func encodeUTF16BE(s string) string {
+	units := utf16.Encode([]rune(s))
+	b := make([]byte, 0, 2+2*len(units))
+	b = append(b, 0xFE, 0xFF)
+	for _, u := range units {
+		b = append(b, byte(u>>8), byte(u))
+	}
+	return string(b)
+}
+
+// encodeLatin1 converts s to ISO-8859-1, replacing characters it can't
+// represent with '?'. It returns how many were replaced.
+func encodeLatin1(s string) (string, int) {
+	b := make([]byte, 0, len(s))
+	replaced := 0
+	for _, r := range s {
+		if r > 0xFF {
+			r = '?'
+			replaced++
+		}
+		b = append(b, byte(r))
+	}
+	return string(b), replaced
+}
+
+// encodeText converts UTF-8 s to the named encoding from fileEncodings.
+// If bom is set, UTF-16 output starts with a byte order mark, which
+// Windows tools expect at the start of a file (and only there).
+func encodeText(s, name string, bom bool) (string, int, error) {
+	switch enc := fileEncodings[strings.ToLower(name)]; enc {
+	case "utf-8":
+		return s, 0, nil
+	case "utf-16le", "utf-16be":
+		out := encodeUTF16LE(s)
+		if enc == "utf-16be" {
+			out = encodeUTF16BE(s)
+		}
+		if !bom {
+			out = out[2:]
+		}
+		return out, 0, nil
+	case "latin1":
+		out, n := encodeLatin1(s)
+		return out, n, nil
+	}
+	return "", 0, fmt.Errorf("unknown encoding %q (want utf-8, utf-16le, utf-16be or latin1)", name)
+}
+
+// startsFile reports whether a write to path begins the file: it isn't
+// appending, or the file is missing or empty. Only such a write gets a
+// byte order mark; one in the middle of a file is read as a stray U+FEFF.
+func startsFile(path string, appendMode bool) bool {
+	if !appendMode {
+		return true
+	}
+	info, err := os.Stat(path)
+	return err != nil || info.Size() == 0
+}
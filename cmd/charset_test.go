@@ -0,0 +1,156 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"unicode/utf16"
+)
+
+// decodeText reverses encodeText for the test: it reads a leading BOM, if
+// any, and decodes the rest in the named encoding.
+func decodeText(t *testing.T, b []byte, name string) (s string, boms int) {
+	t.Helper()
+	if name == "latin1" {
+		r := make([]rune, len(b))
+		for i, c := range b {
+			r[i] = rune(c)
+		}
+		return string(r), 0
+	}
+	if len(b)%2 != 0 {
+		t.Fatalf("odd UTF-16 length %d", len(b))
+	}
+	units := make([]uint16, len(b)/2)
+	for i := range units {
+		if name == "utf-16be" {
+			units[i] = uint16(b[2*i])<<8 | uint16(b[2*i+1])
+		} else {
+			units[i] = uint16(b[2*i+1])<<8 | uint16(b[2*i])
+		}
+	}
+	for _, u := range units {
+		if u == 0xFEFF {
+			boms++
+		}
+	}
+	if len(units) > 0 && units[0] == 0xFEFF {
+		units = units[1:]
+	}
+	return string(utf16.Decode(units)), boms
+}
+
+func TestEncodeTextRoundTrip(t *testing.T) {
+	tests := []struct {
+		name, in string
+	}{
+		{"utf-16le", "héllo wörld\r\n"},
+		{"utf-16be", "héllo wörld\r\n"},
+		{"utf-16le", "emoji 🙂 needs a surrogate pair"},
+		{"UTF16BE", "case-insensitive name"},
+		{"latin1", "café ÿ"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			enc, replaced, err := encodeText(tt.in, tt.name, true)
+			if err != nil || replaced != 0 {
+				t.Fatalf("encodeText: replaced %d, err %v", replaced, err)
+			}
+			got, _ := decodeText(t, []byte(enc), fileEncodings[strings.ToLower(tt.name)])
+			if got != tt.in {
+				t.Errorf("decoded %q, want %q", got, tt.in)
+			}
+		})
+	}
+}
+
+func TestEncodeTextBOM(t *testing.T) {
+	tests := []struct {
+		name string
+		bom  bool
+		want string
+	}{
+		{"utf-16le", true, "\xff\xfea\x00"},
+		{"utf-16le", false, "a\x00"},
+		{"utf-16be", true, "\xfe\xff\x00a"},
+		{"utf-16be", false, "\x00a"},
+		{"utf-8", true, "a"},
+		{"latin1", true, "a"},
+	}
+	for _, tt := range tests {
+		got, _, err := encodeText("a", tt.name, tt.bom)
+		if err != nil || got != tt.want {
+			t.Errorf("encodeText(a, %s, %v) = %q, %v; want %q", tt.name, tt.bom, got, err, tt.want)
+		}
+	}
+}
+
+func TestEncodeTextLatin1Replaced(t *testing.T) {
+	got, replaced, err := encodeText("a€b🙂", "latin1", true)
+	if err != nil || got != "a?b?" || replaced != 2 {
+		t.Errorf("got %q, %d replaced, err %v; want \"a?b?\", 2", got, replaced, err)
+	}
+	if _, _, err := encodeText("x", "ebcdic", true); err == nil {
+		t.Error("unknown encoding accepted")
+	}
+}
+
+// TestAppendedUTF16 appends several entries the way -a --to-encoding does
+// and checks the file decodes back to their concatenation with a single
+// byte order mark at the start.
+func TestAppendedUTF16(t *testing.T) {
+	for _, name := range []string{"utf-16le", "utf-16be"} {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "log.txt")
+			entries := []string{"first\n", "zweite ü\n", "third 🙂\n"}
+			for _, e := range entries {
+				enc, _, err := encodeText(e, name, startsFile(path, true))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if err := writeToFile(path, enc, fileOptions{appendMode: true, mode: 0o644}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			b, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, boms := decodeText(t, b, name)
+			if want := entries[0] + entries[1] + entries[2]; got != want {
+				t.Errorf("decoded %q, want %q", got, want)
+			}
+			if boms != 1 {
+				t.Errorf("file has %d byte order marks, want 1", boms)
+			}
+		})
+	}
+}
+
+func TestStartsFile(t *testing.T) {
+	dir := t.TempDir()
+	empty := filepath.Join(dir, "empty")
+	full := filepath.Join(dir, "full")
+	if err := os.WriteFile(empty, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(full, []byte("x"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		path       string
+		appendMode bool
+		want       bool
+	}{
+		{filepath.Join(dir, "missing"), true, true},
+		{empty, true, true},
+		{full, true, false},
+		{full, false, true},
+	}
+	for _, tt := range tests {
+		if got := startsFile(tt.path, tt.appendMode); got != tt.want {
+			t.Errorf("startsFile(%s, %v) = %v, want %v", filepath.Base(tt.path), tt.appendMode, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,16 @@
+package main
+
+import (
+	"fmt"
+	"os/exec"
+)
+
+// storeInCliphist adds content to cliphist's history by piping it to
+// `cliphist store`, as wl-paste --watch would. env is as for writeUsingCmd.
+func storeInCliphist(content string, env []string) error {
+	bin, err := exec.LookPath("cliphist")
+	if err != nil {
+		return fmt.Errorf("cliphist not found: %w", err)
+	}
+	return writeUsingCmd(bin, []string{"store"}, env, content)
+}
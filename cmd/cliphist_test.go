@@ -0,0 +1,42 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestStoreInCliphist(t *testing.T) {
+	tests := []struct {
+		name    string
+		exit    int // -1 means cliphist is not installed
+		wantErr string
+	}{
+		{"stored", 0, ""},
+		{"store fails", 1, "cliphist"},
+		{"not installed", -1, "cliphist not found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := isolateClipboard(t)
+			if tt.exit >= 0 {
+				fakeHelper(t, dir, "cliphist", tt.exit)
+			}
+			err := storeInCliphist("copied text\n", nil)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := helperArgs(dir, "cliphist"); got != "store" {
+				t.Errorf("cliphist args %q, want store", got)
+			}
+			if got := helperInput(dir, "cliphist"); got != "copied text\n" {
+				t.Errorf("cliphist got %q", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"strings"
+)
+
+// langByExt maps file extensions to Markdown code block languages.
+var langByExt = map[string]string{
+	".bash": "bash", ".c": "c", ".cc": "cpp", ".cpp": "cpp", ".cs": "csharp",
+	".css": "css", ".diff": "diff", ".go": "go", ".h": "c", ".hpp": "cpp",
+	".html": "html", ".java": "java", ".js": "javascript", ".json": "json",
+	".kt": "kotlin", ".lua": "lua", ".md": "markdown", ".patch": "diff",
+	".php": "php", ".pl": "perl", ".py": "python", ".rb": "ruby", ".rs": "rust",
+	".sh": "bash", ".sql": "sql", ".swift": "swift", ".toml": "toml",
+	".ts": "typescript", ".tsx": "tsx", ".xml": "xml", ".yaml": "yaml",
+	".yml": "yaml", ".zsh": "zsh",
+}
+
+// langByInterpreter maps shebang interpreters to languages.
+var langByInterpreter = map[string]string{
+	"bash": "bash", "sh": "sh", "zsh": "zsh", "python": "python",
+	"python3": "python", "node": "javascript", "ruby": "ruby", "perl": "perl",
+	"php": "php", "lua": "lua",
+}
+
+// guessLang picks a code block language from the file name, a shebang line
+// or, failing those, a few recognisable shapes of content. It returns "" if
+// nothing matches.
+func guessLang(filename, content string) string {
+	if lang, ok := langByExt[strings.ToLower(filepath.Ext(filename))]; ok {
+		return lang
+	}
+	if line, ok := strings.CutPrefix(firstLine(content), "#!"); ok {
+		fields := strings.Fields(line)
+		if len(fields) > 0 {
+			interp := filepath.Base(fields[0])
+			if interp == "env" && len(fields) > 1 {
+				interp = fields[1]
+			}
+			if lang, ok := langByInterpreter[interp]; ok {
+				return lang
+			}
+		}
+	}
+	trimmed := strings.TrimSpace(content)
+	switch {
+	case strings.HasPrefix(trimmed, "<?php"):
+		return "php"
+	case strings.HasPrefix(trimmed, "package ") && strings.Contains(trimmed, "\nfunc "):
+		return "go"
+	case strings.HasPrefix(trimmed, "diff --git ") || strings.HasPrefix(trimmed, "--- "):
+		return "diff"
+	case (strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[")) && json.Valid([]byte(trimmed)):
+		return "json"
+	}
+	return ""
+}
+
+// codeBlock wraps s in a fenced Markdown code block tagged with lang. The
+// fence is made longer than any run of backticks in s so it can't be closed
+// early.
+func codeBlock(s, lang string) string {
+	longest, run := 0, 0
+	for _, r := range s {
+		if r == '`' {
+			run++
+			longest = max(longest, run)
+		} else {
+			run = 0
+		}
+	}
+	fence := strings.Repeat("`", max(3, longest+1))
+	if s != "" && !strings.HasSuffix(s, "\n") {
+		s += "\n"
+	}
+	return fence + lang + "\n" + s + fence
+}
@@ -0,0 +1,45 @@
+package main
+
+import "testing"
+
+func TestCodeBlock(t *testing.T) {
+	tests := []struct {
+		name, in, lang, want string
+	}{
+		{"plain", "x := 1\n", "go", "```go\nx := 1\n```"},
+		{"no trailing newline", "x", "", "```\nx\n```"},
+		{"empty", "", "", "```\n```"},
+		{"inline backticks", "use `go vet`\n", "", "```\nuse `go vet`\n```"},
+		{"a fence inside", "```sh\nls\n```\n", "markdown", "````markdown\n```sh\nls\n```\n````"},
+		{"a longer fence inside", "`````\n", "", "``````\n`````\n``````"},
+	}
+	for _, tt := range tests {
+		if got := codeBlock(tt.in, tt.lang); got != tt.want {
+			t.Errorf("%s: codeBlock(%q, %q) = %q, want %q", tt.name, tt.in, tt.lang, got, tt.want)
+		}
+	}
+}
+
+func TestGuessLang(t *testing.T) {
+	tests := []struct {
+		name, filename, content, want string
+	}{
+		{"extension", "main.go", "anything", "go"},
+		{"upper-case extension", "SCRIPT.PY", "", "python"},
+		{"extension wins over shebang", "run.rb", "#!/bin/bash\n", "ruby"},
+		{"shebang", "", "#!/bin/bash\necho hi\n", "bash"},
+		{"env shebang", "", "#!/usr/bin/env python3\nprint(1)\n", "python"},
+		{"unknown shebang", "", "#!/usr/bin/awk -f\n", ""},
+		{"Go source", "", "package main\n\nfunc main() {}\n", "go"},
+		{"PHP", "", "<?php echo 1;", "php"},
+		{"diff", "", "diff --git a/x b/x\n", "diff"},
+		{"JSON", "", `  {"a": [1, 2]}` + "\n", "json"},
+		{"brace but not JSON", "", "{ not json }", ""},
+		{"plain text", "notes", "just some words", ""},
+	}
+	for _, tt := range tests {
+		if got := guessLang(tt.filename, tt.content); got != tt.want {
+			t.Errorf("%s: guessLang(%q, %q) = %q, want %q", tt.name, tt.filename, tt.content, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// decodeBase64URL decodes URL-safe base64 (RFC 4648 §5), with or without
+// padding. Surrounding whitespace, such as a trailing newline, is ignored.
+func decodeBase64URL(s string) (string, error) {
+	s = strings.TrimRight(strings.TrimSpace(s), "=")
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return "", fmt.Errorf("invalid base64url: %w", err)
+	}
+	return string(b), nil
+}
+
+// decodeHex decodes hex digits in either case. Whitespace anywhere is
+// ignored, so grouped dumps like "de ad be ef" decode too.
+func decodeHex(s string) (string, error) {
+	s = strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) {
+			return -1
+		}
+		return r
+	}, s)
+	b, err := hex.DecodeString(s)
+	var invalid hex.InvalidByteError
+	switch {
+	case errors.As(err, &invalid):
+		return "", fmt.Errorf("invalid hex: %q is not a hex digit", rune(invalid))
+	case errors.Is(err, hex.ErrLength):
+		return "", fmt.Errorf("invalid hex: odd number of digits (%d)", len(s))
+	case err != nil:
+		return "", fmt.Errorf("invalid hex: %w", err)
+	}
+	return string(b), nil
+}
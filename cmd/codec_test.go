@@ -0,0 +1,135 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDecodeBase64URL(t *testing.T) {
+	tests := []struct {
+		in, want, wantErr string
+	}{
+		{"aGk", "hi", ""},
+		{"aGk=", "hi", ""},
+		{"aGk=\n", "hi", ""},
+		{"  -__-\n", "\xfb\xff\xfe", ""},
+		{"", "", ""},
+		{"+//+", "", "invalid base64url"},
+		{"a", "", "invalid base64url"},
+		{"aG k", "", "invalid base64url"},
+	}
+	for _, tt := range tests {
+		got, err := decodeBase64URL(tt.in)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("decodeBase64URL(%q) err = %v, want %q", tt.in, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("decodeBase64URL(%q) = %q, %v, want %q", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+// runCodec runs goclip on in with args and returns what it wrote to -f.
+func runCodec(t *testing.T, in string, args ...string) string {
+	t.Helper()
+	out := filepath.Join(t.TempDir(), "out.bin")
+	args = append([]string{"-q", "--no-clip", "-f", out}, args...)
+	if _, stderr, err := runGoclip(t, in, args...); err != nil {
+		t.Fatalf("goclip %v: %v\n%s", args, err, stderr)
+	}
+	b, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestBase64URLRoundTrip(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"\xfb\xff\xfe", "-__-"}, // "+//+" in standard base64
+		{"hi", "aGk="},
+		{"https://example.com/?q=a+b&x=/", "aHR0cHM6Ly9leGFtcGxlLmNvbS8_cT1hK2ImeD0v"},
+		{"\x00\x01binary\xff", "AAFiaW5hcnn_"},
+	}
+	for _, tt := range tests {
+		enc := runCodec(t, tt.in, "--encode-base64url")
+		if enc != tt.want {
+			t.Errorf("%q encoded to %q, want %q", tt.in, enc, tt.want)
+		}
+		if strings.ContainsAny(enc, "+/") {
+			t.Errorf("%q encoded to %q, which isn't URL-safe", tt.in, enc)
+		}
+		if dec := runCodec(t, enc+"\n", "--decode-base64url", "--binary"); dec != tt.in {
+			t.Errorf("%q decoded back to %q", enc, dec)
+		}
+	}
+	if _, stderr, err := runGoclip(t, "a+b/", "-q", "--no-clip", "--decode-base64url"); err == nil || !strings.Contains(stderr, "invalid base64url") {
+		t.Errorf("standard alphabet: err = %v, stderr %q", err, stderr)
+	}
+}
+
+func TestDecodeHex(t *testing.T) {
+	tests := []struct {
+		in, want, wantErr string
+	}{
+		{"6869", "hi", ""},
+		{"DEADbeef", "\xde\xad\xbe\xef", ""},
+		{"de ad\nbe\tef\n", "\xde\xad\xbe\xef", ""},
+		{"", "", ""},
+		{"abc", "", "odd number of digits (3)"},
+		{"a b c", "", "odd number of digits (3)"},
+		{"zz", "", `'z' is not a hex digit`},
+		{"0x41", "", `'x' is not a hex digit`},
+	}
+	for _, tt := range tests {
+		got, err := decodeHex(tt.in)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("decodeHex(%q) err = %v, want %q", tt.in, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("decodeHex(%q) = %q, %v, want %q", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+func TestHexRoundTrip(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"hi\n", "68690a"},
+		{"\x00\xffbin", "00ff62696e"},
+		{"\x1b[31mred\x1b[0m", "1b5b33316d7265641b5b306d"}, // escapes kept
+		{"é", "c3a9"},
+	}
+	for _, tt := range tests {
+		enc := runCodec(t, tt.in, "--hex")
+		if enc != tt.want {
+			t.Errorf("%q encoded to %q, want %q", tt.in, enc, tt.want)
+		}
+		if dec := runCodec(t, enc+"\n", "--unhex", "--binary", "-s=false"); dec != tt.in {
+			t.Errorf("%q decoded back to %q", enc, dec)
+		}
+	}
+
+	for _, tt := range []struct{ in, wantErr string }{
+		{"abc", "odd number of digits"},
+		{"ghij", "is not a hex digit"},
+	} {
+		if _, stderr, err := runGoclip(t, tt.in, "-q", "--no-clip", "--unhex"); err == nil || !strings.Contains(stderr, tt.wantErr) {
+			t.Errorf("--unhex %q: err = %v, stderr %q, want %q", tt.in, err, stderr, tt.wantErr)
+		}
+	}
+	if _, _, err := runGoclip(t, "x", "-q", "--no-clip", "--hex", "--unhex"); err == nil {
+		t.Error("--hex with --unhex: want an error")
+	}
+}
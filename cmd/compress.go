@@ -0,0 +1,57 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// compressedMarker prefixes clipboard content written by --compress. The
+// format is goclip's own, not a clipboard standard: only
+// "goclip paste --decompress" turns it back into text.
+const compressedMarker = "goclip-gzip:"
+
+// compressContent gzips content and returns it base64-encoded behind
+// compressedMarker.
+func compressContent(content string) (string, error) {
+	var buf bytes.Buffer
+	zw, err := gzip.NewWriterLevel(&buf, gzip.BestCompression)
+	if err != nil {
+		return "", err
+	}
+	if _, err := io.WriteString(zw, content); err != nil {
+		return "", err
+	}
+	if err := zw.Close(); err != nil {
+		return "", err
+	}
+	return compressedMarker + base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
+
+// decompressContent reverses compressContent. Content without the marker is
+// returned unchanged.
+func decompressContent(s string) (string, error) {
+	enc, ok := strings.CutPrefix(strings.TrimSpace(s), compressedMarker)
+	if !ok {
+		return s, nil
+	}
+	data, err := base64.StdEncoding.DecodeString(enc)
+	if err != nil {
+		return "", fmt.Errorf("decode compressed content: %w", err)
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return "", fmt.Errorf("decompress: %w", err)
+	}
+	out, err := io.ReadAll(io.LimitReader(zr, maxBufferSize+1))
+	if err != nil {
+		return "", fmt.Errorf("decompress: %w", err)
+	}
+	if len(out) > maxBufferSize {
+		return "", fmt.Errorf("decompressed content exceeds %d MB", maxBufferSize>>20)
+	}
+	return string(out), nil
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+func TestCompressRoundTrip(t *testing.T) {
+	big := strings.Repeat("2024-01-01 INFO request handled in 12ms\n", 5000)
+	for _, s := range []string{"", "short", "héllo 🙂\n", big} {
+		enc, err := compressContent(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasPrefix(enc, compressedMarker) {
+			t.Errorf("compressed %d bytes without the marker", len(s))
+		}
+		got, err := decompressContent(enc + "\n") // pasted with a newline
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != s {
+			t.Errorf("round trip of %d bytes gave %d bytes", len(s), len(got))
+		}
+	}
+	enc, _ := compressContent(big)
+	if len(enc)*10 > len(big) {
+		t.Errorf("repetitive log compressed to %d of %d bytes", len(enc), len(big))
+	}
+}
+
+func TestDecompressContent(t *testing.T) {
+	tests := []struct {
+		name, in, want, wantErr string
+	}{
+		{"no marker", "plain text\n", "plain text\n", ""},
+		{"bad base64", compressedMarker + "not base64!", "", "decode compressed content"},
+		{"not gzip", compressedMarker + base64.StdEncoding.EncodeToString([]byte("plain")), "", "decompress"},
+	}
+	for _, tt := range tests {
+		got, err := decompressContent(tt.in)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("%s: err = %v, want %q", tt.name, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("%s: got %q, %v, want %q", tt.name, got, err, tt.want)
+		}
+	}
+}
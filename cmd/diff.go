@@ -0,0 +1,223 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// diffContext is the number of unchanged lines shown around each change.
+const diffContext = 3
+
+// diffOp is one line of an edit script: ' ' (kept), '-' (removed from a)
+// or '+' (added from b).
+type diffOp struct {
+	kind byte
+	text string
+}
+
+// diffMaxCost caps the edit distance diffLines searches for in one
+// subproblem. Beyond it the subproblem is reported as removed and re-added
+// as a whole, which is still a correct (if not minimal) diff and keeps
+// unrelated inputs from taking quadratic time.
+const diffMaxCost = 2000
+
+// diffLines computes an edit script turning a into b. It uses the
+// linear-space variant of Myers' algorithm: find the middle snake of the
+// shortest edit path, then recurse on either side of it, so memory stays
+// proportional to len(a)+len(b).
+func diffLines(a, b []string) []diffOp {
+	var ops []diffOp
+	diffInto(&ops, a, b)
+	return ops
+}
+
+// diffInto appends the edit script for a and b to ops.
+func diffInto(ops *[]diffOp, a, b []string) {
+	// Common prefix and suffix need no search.
+	pre := 0
+	for pre < len(a) && pre < len(b) && a[pre] == b[pre] {
+		pre++
+	}
+	suf := 0
+	for suf < len(a)-pre && suf < len(b)-pre && a[len(a)-1-suf] == b[len(b)-1-suf] {
+		suf++
+	}
+	for _, line := range a[:pre] {
+		*ops = append(*ops, diffOp{' ', line})
+	}
+	midA, midB := a[pre:len(a)-suf], b[pre:len(b)-suf]
+
+	if x, y, ok := middleSnake(midA, midB); ok {
+		diffInto(ops, midA[:x], midB[:y])
+		diffInto(ops, midA[x:], midB[y:])
+	} else {
+		for _, line := range midA {
+			*ops = append(*ops, diffOp{'-', line})
+		}
+		for _, line := range midB {
+			*ops = append(*ops, diffOp{'+', line})
+		}
+	}
+
+	for _, line := range a[len(a)-suf:] {
+		*ops = append(*ops, diffOp{' ', line})
+	}
+}
+
+// middleSnake runs Myers' search from both ends of a and b at once and
+// returns the point where the two paths meet, splitting the problem in
+// two. a and b must not share a first or last line. ok is false if either
+// is empty, or if no split was found within diffMaxCost.
+func middleSnake(a, b []string) (int, int, bool) {
+	n, m := len(a), len(b)
+	if n == 0 || m == 0 {
+		return 0, 0, false
+	}
+	maxD := min((n+m+1)/2, diffMaxCost)
+	off := maxD + 1
+	vf := make([]int, 2*off+1) // furthest x reached from the start, per diagonal
+	vb := make([]int, 2*off+1) // furthest distance from the end, per diagonal
+	for i := range vf {
+		vf[i], vb[i] = -1, -1
+	}
+	vf[off+1], vb[off+1] = 0, 0
+	delta := n - m
+	odd := delta%2 != 0
+	// Diagonals that ran off the edge of the grid are skipped.
+	fStart, fEnd, bStart, bEnd := 0, 0, 0, 0
+
+	for d := 0; d < maxD; d++ {
+		for k := -d + fStart; k <= d-fEnd; k += 2 {
+			var x int
+			if k == -d || (k != d && vf[off+k-1] < vf[off+k+1]) {
+				x = vf[off+k+1]
+			} else {
+				x = vf[off+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			vf[off+k] = x
+			switch {
+			case x > n:
+				fEnd += 2
+			case y > m:
+				fStart += 2
+			case odd:
+				if kb := delta - k; kb > -d && kb < d && vb[off+kb] != -1 && x >= n-vb[off+kb] {
+					return x, y, true
+				}
+			}
+		}
+		for k := -d + bStart; k <= d-bEnd; k += 2 {
+			var x int
+			if k == -d || (k != d && vb[off+k-1] < vb[off+k+1]) {
+				x = vb[off+k+1]
+			} else {
+				x = vb[off+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[n-x-1] == b[m-y-1] {
+				x++
+				y++
+			}
+			vb[off+k] = x
+			switch {
+			case x > n:
+				bEnd += 2
+			case y > m:
+				bStart += 2
+			case !odd:
+				if kf := delta - k; kf >= -d && kf <= d && vf[off+kf] != -1 {
+					fx := vf[off+kf]
+					if fx >= n-x {
+						return fx, fx - kf, true
+					}
+				}
+			}
+		}
+	}
+	return 0, 0, false
+}
+
+// unifiedDiff renders the differences between a and b in unified diff
+// format. It returns "" if they are identical.
+func unifiedDiff(aName, bName, a, b string) string {
+	aLines, _ := splitLines(a)
+	bLines, _ := splitLines(b)
+	ops := diffLines(aLines, bLines)
+
+	var out strings.Builder
+	for i := 0; i < len(ops); {
+		// Find the next change.
+		for i < len(ops) && ops[i].kind == ' ' {
+			i++
+		}
+		if i == len(ops) {
+			break
+		}
+		start := max(i-diffContext, 0)
+		end := i
+		for end < len(ops) {
+			if ops[end].kind != ' ' {
+				end++
+				continue
+			}
+			// Stop once a run of unchanged lines is long enough to
+			// separate this hunk from the next one.
+			run := end
+			for run < len(ops) && ops[run].kind == ' ' {
+				run++
+			}
+			if run == len(ops) || run-end > 2*diffContext {
+				end = min(end+diffContext, len(ops))
+				break
+			}
+			end = run
+		}
+
+		aStart, bStart := 1, 1
+		for _, op := range ops[:start] {
+			if op.kind != '+' {
+				aStart++
+			}
+			if op.kind != '-' {
+				bStart++
+			}
+		}
+		aCount, bCount := 0, 0
+		for _, op := range ops[start:end] {
+			if op.kind != '+' {
+				aCount++
+			}
+			if op.kind != '-' {
+				bCount++
+			}
+		}
+		if out.Len() == 0 {
+			fmt.Fprintf(&out, "--- %s\n+++ %s\n", aName, bName)
+		}
+		fmt.Fprintf(&out, "@@ -%s +%s @@\n", hunkRange(aStart, aCount), hunkRange(bStart, bCount))
+		for _, op := range ops[start:end] {
+			out.WriteByte(op.kind)
+			out.WriteString(op.text)
+			out.WriteByte('\n')
+		}
+		i = end
+	}
+	return out.String()
+}
+
+// hunkRange formats a hunk header range; empty ranges point at the line
+// before them as diff(1) does.
+func hunkRange(start, count int) string {
+	if count == 0 {
+		start--
+	}
+	if count == 1 {
+		return fmt.Sprint(start)
+	}
+	return fmt.Sprintf("%d,%d", start, count)
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"math/rand/v2"
+	"strings"
+	"testing"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want string
+	}{
+		{"identical", "a\nb\n", "a\nb\n", ""},
+		{"changed line", "one\ntwo\nthree\n", "one\n2\nthree\n",
+			"--- last copy\n+++ input\n@@ -1,3 +1,3 @@\n one\n-two\n+2\n three\n"},
+		{"added at end", "a\n", "a\nb\n",
+			"--- last copy\n+++ input\n@@ -1 +1,2 @@\n a\n+b\n"},
+		{"from empty", "", "x\n",
+			"--- last copy\n+++ input\n@@ -0,0 +1 @@\n+x\n"},
+		{"separate hunks", "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n", "0\n2\n3\n4\n5\n6\n7\n8\n9\n11\n",
+			"--- last copy\n+++ input\n@@ -1,4 +1,4 @@\n-1\n+0\n 2\n 3\n 4\n@@ -7,4 +7,4 @@\n 7\n 8\n 9\n-10\n+11\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := unifiedDiff("last copy", "input", tt.a, tt.b); got != tt.want {
+				t.Errorf("got\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+// applyOps checks that ops turn a into b and returns the number of edits.
+func applyOps(t *testing.T, a, b []string, ops []diffOp) int {
+	t.Helper()
+	var gotA, gotB []string
+	edits := 0
+	for _, op := range ops {
+		if op.kind != '+' {
+			gotA = append(gotA, op.text)
+		}
+		if op.kind != '-' {
+			gotB = append(gotB, op.text)
+		}
+		if op.kind != ' ' {
+			edits++
+		}
+	}
+	if strings.Join(gotA, "\n") != strings.Join(a, "\n") || strings.Join(gotB, "\n") != strings.Join(b, "\n") {
+		t.Fatalf("script doesn't reproduce the inputs: a=%q b=%q ops=%v", a, b, ops)
+	}
+	return edits
+}
+
+// lcsEdits is the minimal edit count by dynamic programming.
+func lcsEdits(a, b []string) int {
+	dp := make([][]int, len(a)+1)
+	for i := range dp {
+		dp[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				dp[i][j] = dp[i+1][j+1] + 1
+			} else {
+				dp[i][j] = max(dp[i+1][j], dp[i][j+1])
+			}
+		}
+	}
+	return len(a) + len(b) - 2*dp[0][0]
+}
+
+func TestDiffLinesMinimal(t *testing.T) {
+	r := rand.New(rand.NewPCG(1, 2))
+	randLines := func() []string {
+		lines := make([]string, r.IntN(12))
+		for i := range lines {
+			lines[i] = string(rune('a' + r.IntN(3)))
+		}
+		return lines
+	}
+	for range 2000 {
+		a, b := randLines(), randLines()
+		edits := applyOps(t, a, b, diffLines(a, b))
+		if want := lcsEdits(a, b); edits != want {
+			t.Fatalf("a=%q b=%q: %d edits, minimal is %d", a, b, edits, want)
+		}
+	}
+}
+
+func TestDiffLinesLargeUnrelated(t *testing.T) {
+	a := make([]string, 20000)
+	b := make([]string, 20000)
+	for i := range a {
+		a[i] = "a" + strings.Repeat("x", i%7) + string(rune(i))
+		b[i] = "b" + string(rune(i))
+	}
+	// Also an input with a few scattered edits, which must stay minimal.
+	c := append([]string(nil), a...)
+	for i := 0; i < len(c); i += 1000 {
+		c[i] = "changed"
+	}
+	applyOps(t, a, b, diffLines(a, b))
+	if edits := applyOps(t, a, c, diffLines(a, c)); edits != 40 {
+		t.Errorf("scattered edits: %d, want 40", edits)
+	}
+}
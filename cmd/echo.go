@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"regexp"
+	"time"
+)
+
+// prefixWriter writes everything to w, inserting the result of prefix() at
+// the start of every line. It is used for the stdout echo only, so the
+// captured content is never affected.
+type prefixWriter struct {
+	w       io.Writer
+	prefix  func() string
+	midLine bool
+}
+
+func (pw *prefixWriter) Write(p []byte) (int, error) {
+	for rest := p; len(rest) > 0; {
+		if !pw.midLine {
+			if _, err := io.WriteString(pw.w, pw.prefix()); err != nil {
+				return len(p) - len(rest), err
+			}
+		}
+		chunk := rest
+		if i := bytes.IndexByte(rest, '\n'); i >= 0 {
+			chunk = rest[:i+1]
+		}
+		if _, err := pw.w.Write(chunk); err != nil {
+			return len(p) - len(rest), err
+		}
+		pw.midLine = chunk[len(chunk)-1] != '\n'
+		rest = rest[len(chunk):]
+	}
+	return len(p), nil
+}
+
+// syncWriter writes to f and syncs it after every write that ends a line.
+// os.Stdout is not buffered, so the echo already leaves goclip as each read
+// completes; syncing also pushes it through to disk when stdout is a file
+// that another process is following. Sync errors (a pipe or terminal
+// can't be synced) are ignored.
+type syncWriter struct {
+	f *os.File
+}
+
+func (sw syncWriter) Write(p []byte) (int, error) {
+	n, err := sw.f.Write(p)
+	if err == nil && bytes.IndexByte(p, '\n') >= 0 {
+		_ = sw.f.Sync()
+	}
+	return n, err
+}
+
+// timestampPrefix returns the current wall-clock time as an echo prefix.
+func timestampPrefix() string {
+	return time.Now().Format("15:04:05.000") + " "
+}
+
+// highlightStart and highlightEnd wrap regex matches in the stdout echo.
+const (
+	highlightStart = "\x1b[1;31m"
+	highlightEnd   = "\x1b[0m"
+)
+
+// highlightWriter colours matches of re in whatever is written through it.
+// It works a line at a time so matches can't be split across writes; call
+// Flush to emit a final unterminated line.
+type highlightWriter struct {
+	w       io.Writer
+	re      *regexp.Regexp
+	pending []byte
+}
+
+func (hw *highlightWriter) Write(p []byte) (int, error) {
+	hw.pending = append(hw.pending, p...)
+	i := bytes.LastIndexByte(hw.pending, '\n')
+	if i < 0 {
+		return len(p), nil
+	}
+	if _, err := hw.w.Write(hw.highlight(hw.pending[:i+1])); err != nil {
+		return 0, err
+	}
+	hw.pending = append(hw.pending[:0], hw.pending[i+1:]...)
+	return len(p), nil
+}
+
+// Flush writes any buffered partial line.
+func (hw *highlightWriter) Flush() error {
+	if len(hw.pending) == 0 {
+		return nil
+	}
+	_, err := hw.w.Write(hw.highlight(hw.pending))
+	hw.pending = hw.pending[:0]
+	return err
+}
+
+// highlight colours matches line by line, so anchors behave as in --grep.
+func (hw *highlightWriter) highlight(b []byte) []byte {
+	var out []byte
+	for line := range bytes.Lines(b) {
+		body := bytes.TrimSuffix(line, []byte("\n"))
+		out = append(out, hw.re.ReplaceAllFunc(body, func(m []byte) []byte {
+			if len(m) == 0 {
+				return m
+			}
+			return append(append([]byte(highlightStart), m...), highlightEnd...)
+		})...)
+		out = append(out, line[len(body):]...)
+	}
+	return out
+}
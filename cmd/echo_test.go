@@ -0,0 +1,196 @@
+package main
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+)
+
+func TestPrefixWriter(t *testing.T) {
+	tests := []struct {
+		name   string
+		writes []string
+		want   string
+	}{
+		{"one write", []string{"a\nb\n"}, "> a\n> b\n"},
+		{"line split across writes", []string{"he", "llo\nwor", "ld\n"}, "> hello\n> world\n"},
+		{"unterminated last line", []string{"a\nb"}, "> a\n> b"},
+		{"blank lines", []string{"\n\n"}, "> \n> \n"},
+		{"nothing written", nil, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out strings.Builder
+			pw := &prefixWriter{w: &out, prefix: func() string { return "> " }}
+			for _, w := range tt.writes {
+				if n, err := pw.Write([]byte(w)); n != len(w) || err != nil {
+					t.Fatalf("Write(%q) = %d, %v", w, n, err)
+				}
+			}
+			if out.String() != tt.want {
+				t.Errorf("got %q, want %q", out.String(), tt.want)
+			}
+		})
+	}
+}
+
+// TestEchoTimestampContent reads through a timestamping echo, as
+// --echo-timestamp does: the echo is prefixed, the content is not.
+func TestEchoTimestampContent(t *testing.T) {
+	const in = "first line\nsecond line\n"
+	var echo strings.Builder
+	content, _, err := readInput(readConfig{
+		in:    iotest.OneByteReader(strings.NewReader(in)),
+		echo:  &prefixWriter{w: &echo, prefix: timestampPrefix},
+		limit: maxBufferSize,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if content != in {
+		t.Errorf("content = %q, want %q", content, in)
+	}
+	stamped := regexp.MustCompile(`^\d{2}:\d{2}:\d{2}\.\d{3} (first|second) line$`)
+	lines, _ := splitLines(echo.String())
+	if len(lines) != 2 {
+		t.Fatalf("echo = %q, want 2 lines", echo.String())
+	}
+	for _, line := range lines {
+		if !stamped.MatchString(line) {
+			t.Errorf("echo line %q has no timestamp", line)
+		}
+	}
+}
+
+func TestHighlightWriter(t *testing.T) {
+	const hs, he = highlightStart, highlightEnd
+	tests := []struct {
+		name    string
+		pattern string
+		writes  []string
+		want    string
+	}{
+		{"one match", `err`, []string{"an err here\n"}, "an " + hs + "err" + he + " here\n"},
+		{"several matches", `o`, []string{"foo\n"}, "f" + hs + "o" + he + hs + "o" + he + "\n"},
+		{"match split across writes", `error`, []string{"an er", "ror\nok\n"}, "an " + hs + "error" + he + "\nok\n"},
+		{"anchors per line", `^x`, []string{"xa\nbx\nxc"}, hs + "x" + he + "a\nbx\n" + hs + "x" + he + "c"},
+		{"empty matches left alone", `z*`, []string{"ab\n"}, "ab\n"},
+		{"no match", `nope`, []string{"plain\n"}, "plain\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out strings.Builder
+			hw := &highlightWriter{w: &out, re: regexp.MustCompile(tt.pattern)}
+			for _, w := range tt.writes {
+				if n, err := hw.Write([]byte(w)); n != len(w) || err != nil {
+					t.Fatalf("Write(%q) = %d, %v", w, n, err)
+				}
+			}
+			if err := hw.Flush(); err != nil {
+				t.Fatal(err)
+			}
+			if out.String() != tt.want {
+				t.Errorf("got %q, want %q", out.String(), tt.want)
+			}
+		})
+	}
+}
+
+// TestHighlightContent reads through a highlighting echo, as --highlight
+// does: the echo is coloured, the content for the clipboard stays plain.
+func TestHighlightContent(t *testing.T) {
+	const in = "ok\nfatal error\nlast error"
+	var echo strings.Builder
+	content, _, err := readInput(readConfig{
+		in:    iotest.HalfReader(strings.NewReader(in)),
+		echo:  &highlightWriter{w: &echo, re: regexp.MustCompile(`error`)},
+		limit: maxBufferSize,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if content != in || strings.Contains(content, "\x1b") {
+		t.Errorf("content = %q, want %q", content, in)
+	}
+	want := "ok\nfatal " + highlightStart + "error" + highlightEnd + "\nlast " + highlightStart + "error" + highlightEnd
+	if echo.String() != want {
+		t.Errorf("echo = %q, want %q", echo.String(), want)
+	}
+}
+
+func TestSyncWriter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "echo.txt")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sw := syncWriter{f: f}
+	for _, chunk := range []string{"par", "tial\nnext", " line\n"} {
+		if n, err := sw.Write([]byte(chunk)); err != nil || n != len(chunk) {
+			t.Fatalf("Write(%q) = %d, %v", chunk, n, err)
+		}
+	}
+	if got, _ := os.ReadFile(path); string(got) != "partial\nnext line\n" {
+		t.Errorf("file holds %q", got)
+	}
+	f.Close()
+	if _, err := sw.Write([]byte("x\n")); err == nil {
+		t.Error("write to a closed file: want an error")
+	}
+
+	// A pipe can't be synced; that mustn't fail the write.
+	pr, pw, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pr.Close()
+	if _, err := (syncWriter{f: pw}).Write([]byte("line\n")); err != nil {
+		t.Errorf("pipe: %v", err)
+	}
+	pw.Close()
+}
+
+// TestEchoIsTimely keeps goclip's stdin open after one line and checks
+// that line is echoed before the input ends, with and without --flush.
+func TestEchoIsTimely(t *testing.T) {
+	for _, args := range [][]string{{"--no-clip"}, {"--no-clip", "--flush"}} {
+		inR, inW, err := os.Pipe()
+		if err != nil {
+			t.Fatal(err)
+		}
+		outR, outW, err := os.Pipe()
+		if err != nil {
+			t.Fatal(err)
+		}
+		cmd := goclipCommand(args...)
+		cmd.Stdin, cmd.Stdout = inR, outW
+		if err := cmd.Start(); err != nil {
+			t.Fatal(err)
+		}
+		inR.Close()
+		outW.Close()
+		inW.WriteString("first line\n")
+
+		got := make(chan string, 1)
+		go func() {
+			line, _ := bufio.NewReader(outR).ReadString('\n')
+			got <- line
+		}()
+		select {
+		case line := <-got:
+			if line != "first line\n" {
+				t.Errorf("%v: echoed %q", args, line)
+			}
+		case <-time.After(10 * time.Second):
+			t.Errorf("%v: nothing echoed while the input was still open", args)
+		}
+		inW.Close()
+		cmd.Wait()
+		outR.Close()
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// stubEditor writes a shell script editor to dir running body, with the
+// file to edit in $f, and returns its path. The path of the file is saved
+// to dir/edited.
+func stubEditor(t *testing.T, dir, body string) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("stub editors are shell scripts")
+	}
+	path := filepath.Join(dir, "editor")
+	script := "#!/bin/sh\nfor f; do :; done\necho \"$f\" > '" + filepath.Join(dir, "edited") + "'\n" + body + "\n"
+	if err := os.WriteFile(path, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestEditContent(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		args    string
+		want    string
+		wantErr string
+	}{
+		{"no-op", "exit 0", "", "draft\n", ""},
+		{"appends a line", `echo edited >> "$f"`, "", "draft\nedited\n", ""},
+		{"replaces the text", `printf 'new' > "$f"`, "", "new", ""},
+		{"editor with arguments", `[ "$1" = --wait ] && echo waited >> "$f"`, " --wait", "draft\nwaited\n", ""},
+		{"editor fails", "exit 3", "", "", "exit status 3"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			t.Setenv("EDITOR", stubEditor(t, dir, tt.body)+tt.args)
+			got, err := editContent("draft\n")
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("err = %v, want %q", err, tt.wantErr)
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			} else if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+			edited, err := os.ReadFile(filepath.Join(dir, "edited"))
+			if err != nil {
+				t.Fatal("editor didn't run")
+			}
+			if _, err := os.Stat(strings.TrimSpace(string(edited))); !os.IsNotExist(err) {
+				t.Errorf("temp file %s left behind", strings.TrimSpace(string(edited)))
+			}
+		})
+	}
+}
+
+func TestEditContentNoEditor(t *testing.T) {
+	t.Setenv("EDITOR", "")
+	if _, err := editContent("x"); err == nil || !strings.Contains(err.Error(), "$EDITOR is not set") {
+		t.Errorf("err = %v, want $EDITOR is not set", err)
+	}
+}
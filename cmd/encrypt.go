@@ -0,0 +1,59 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"filippo.io/age"
+)
+
+// passphraseEnv holds the --encrypt passphrase, so it never appears in the
+// process list or shell history.
+const passphraseEnv = "GOCLIP_PASSPHRASE"
+
+// encryptionRecipient returns who --encrypt encrypts to: the age public key
+// given with --recipient, or else the passphrase from passphraseEnv. The
+// output is a standard age file either way, so 'age -d' reads it back.
+func encryptionRecipient(recipient string) (age.Recipient, error) {
+	if recipient != "" {
+		r, err := age.ParseX25519Recipient(strings.TrimSpace(recipient))
+		if err != nil {
+			return nil, fmt.Errorf("invalid --recipient: %w", err)
+		}
+		return r, nil
+	}
+	p := os.Getenv(passphraseEnv)
+	if p == "" {
+		return nil, fmt.Errorf("--encrypt needs a passphrase in $%s, or --recipient", passphraseEnv)
+	}
+	return age.NewScryptRecipient(p)
+}
+
+// encryptContent encrypts data to r in the binary age format.
+func encryptContent(data []byte, r age.Recipient) ([]byte, error) {
+	var buf bytes.Buffer
+	w, err := age.Encrypt(&buf, r)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// decryptContent reverses encryptContent. goclip itself only encrypts; this
+// is what 'age -d' does, kept for the tests.
+func decryptContent(data []byte, id age.Identity) ([]byte, error) {
+	r, err := age.Decrypt(bytes.NewReader(data), id)
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(r)
+}
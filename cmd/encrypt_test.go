@@ -0,0 +1,148 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"filippo.io/age"
+)
+
+// scryptPair returns a passphrase recipient and identity with a low work
+// factor, so the tests don't spend seconds in scrypt.
+func scryptPair(t *testing.T, pass string) (age.Recipient, age.Identity) {
+	t.Helper()
+	r, err := age.NewScryptRecipient(pass)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.SetWorkFactor(10)
+	id, err := age.NewScryptIdentity(pass)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r, id
+}
+
+func TestEncryptRoundTrip(t *testing.T) {
+	key, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	passR, passID := scryptPair(t, "correct horse")
+	tests := []struct {
+		name string
+		r    age.Recipient
+		id   age.Identity
+	}{
+		{"passphrase", passR, passID},
+		{"recipient", key.Recipient(), key},
+	}
+	for _, tt := range tests {
+		for _, plain := range []string{"", "secret\n", strings.Repeat("x", 100000)} {
+			enc, err := encryptContent([]byte(plain), tt.r)
+			if err != nil {
+				t.Fatalf("%s: encrypt: %v", tt.name, err)
+			}
+			if !bytes.HasPrefix(enc, []byte("age-encryption.org/v1\n")) {
+				t.Errorf("%s: output is not an age file: %q", tt.name, enc[:min(len(enc), 40)])
+			}
+			if len(plain) > 0 && bytes.Contains(enc, []byte(plain)) {
+				t.Errorf("%s: ciphertext contains the plaintext", tt.name)
+			}
+			got, err := decryptContent(enc, tt.id)
+			if err != nil {
+				t.Fatalf("%s: decrypt: %v", tt.name, err)
+			}
+			if string(got) != plain {
+				t.Errorf("%s: round trip of %d bytes gave %d bytes", tt.name, len(plain), len(got))
+			}
+		}
+	}
+}
+
+func TestDecryptRejects(t *testing.T) {
+	key, _ := age.GenerateX25519Identity()
+	other, _ := age.GenerateX25519Identity()
+	passR, passID := scryptPair(t, "right")
+	_, wrongID := scryptPair(t, "wrong")
+	byPass, err := encryptContent([]byte("top secret"), passR)
+	if err != nil {
+		t.Fatal(err)
+	}
+	byKey, err := encryptContent([]byte("top secret"), key.Recipient())
+	if err != nil {
+		t.Fatal(err)
+	}
+	flip := func(b []byte, i int) []byte {
+		b = bytes.Clone(b)
+		b[i] ^= 1
+		return b
+	}
+	tests := []struct {
+		name string
+		data []byte
+		id   age.Identity
+	}{
+		{"wrong passphrase", byPass, wrongID},
+		{"wrong identity", byKey, other},
+		{"key for a passphrase file", byPass, key},
+		{"passphrase for a key file", byKey, passID},
+		{"ciphertext changed", flip(byKey, len(byKey)-20), key},
+		{"tag changed", flip(byPass, len(byPass)-1), passID},
+		{"truncated", byKey[:len(byKey)-1], key},
+		{"not encrypted", []byte("plain text"), key},
+		{"empty", nil, key},
+	}
+	for _, tt := range tests {
+		if got, err := decryptContent(tt.data, tt.id); err == nil {
+			t.Errorf("%s: decrypted to %q, want an error", tt.name, got)
+		}
+	}
+}
+
+func TestEncryptionRecipient(t *testing.T) {
+	key, _ := age.GenerateX25519Identity()
+	tests := []struct {
+		name, recipient, pass string
+		wantErr               bool
+	}{
+		{"public key", key.Recipient().String(), "", false},
+		{"public key with spaces", " " + key.Recipient().String() + "\n", "", false},
+		{"passphrase", "", "pass", false},
+		{"neither", "", "", true},
+		{"not a key", "garbage", "pass", true},
+		{"private key", key.String(), "", true},
+	}
+	for _, tt := range tests {
+		t.Setenv(passphraseEnv, tt.pass)
+		if _, err := encryptionRecipient(tt.recipient); (err != nil) != tt.wantErr {
+			t.Errorf("%s: err = %v, want error %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+// TestEncryptFlag runs goclip --encrypt --recipient and checks the -f file
+// is an age file for that key while the clipboard gets the plain text.
+func TestEncryptFlag(t *testing.T) {
+	key, _ := age.GenerateX25519Identity()
+	const content = "hunter2\n"
+	clip, file := copyAndLog(t, content, "--encrypt", "--recipient", key.Recipient().String())
+	if clip != content {
+		t.Errorf("clipboard %q, want %q", clip, content)
+	}
+	got, err := decryptContent([]byte(file), key)
+	if err != nil || string(got) != content {
+		t.Errorf("-f file decrypted to %q, %v; want %q", got, err, content)
+	}
+
+	out := filepath.Join(t.TempDir(), "out.age")
+	if _, _, err := runGoclip(t, content, "-q", "--no-clip", "-f", out, "--encrypt", "--recipient", "garbage"); err == nil {
+		t.Error("--recipient garbage: want an error")
+	}
+	if _, err := os.Stat(out); err == nil {
+		t.Error("-f file written despite a bad --recipient")
+	}
+}
@@ -0,0 +1,23 @@
+package main
+
+import "flag"
+
+// isFlagSet reports whether the named flag was given on the command line,
+// for options whose default depends on other flags.
+func isFlagSet(name string) bool {
+	set := false
+	flag.Visit(func(f *flag.Flag) { set = set || f.Name == name })
+	return set
+}
+
+// countTrue returns how many of flags are set, for mutually exclusive
+// options.
+func countTrue(flags ...bool) int {
+	n := 0
+	for _, f := range flags {
+		if f {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// maxHistoryEntries caps how many copies --history keeps.
+const maxHistoryEntries = 100
+
+// historyEntry is one recorded copy, stored as a JSON line.
+type historyEntry struct {
+	Time    time.Time `json:"time"`
+	Content string    `json:"content"`
+}
+
+// historyPath returns the history file location, honouring XDG_STATE_HOME.
+func historyPath() (string, error) {
+	dir := os.Getenv("XDG_STATE_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("locate history: %w", err)
+		}
+		dir = filepath.Join(home, ".local", "state")
+	}
+	return filepath.Join(dir, "goclip", "history.jsonl"), nil
+}
+
+// loadHistory reads all recorded entries, oldest first. A missing history
+// file is not an error.
+func loadHistory() ([]historyEntry, error) {
+	path, err := historyPath()
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("open history: %w", err)
+	}
+	defer f.Close()
+
+	var entries []historyEntry
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 64*1024), 2*maxBufferSize)
+	for sc.Scan() {
+		var e historyEntry
+		if err := json.Unmarshal(sc.Bytes(), &e); err != nil {
+			continue // skip corrupt lines rather than losing the rest
+		}
+		entries = append(entries, e)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("read history: %w", err)
+	}
+	return entries, nil
+}
+
+// appendHistory records content unless it matches one of the last
+// dedupWindow entries (none if dedupWindow is 0), keeping at most
+// maxHistoryEntries.
+func appendHistory(content string, dedupWindow int) error {
+	entries, err := loadHistory()
+	if err != nil {
+		return err
+	}
+	for _, e := range entries[max(len(entries)-dedupWindow, 0):] {
+		if e.Content == content {
+			return nil
+		}
+	}
+	entries = append(entries, historyEntry{Time: time.Now(), Content: content})
+	if len(entries) > maxHistoryEntries {
+		entries = entries[len(entries)-maxHistoryEntries:]
+	}
+
+	path, err := historyPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("create history dir: %w", err)
+	}
+	var b strings.Builder
+	enc := json.NewEncoder(&b)
+	for _, e := range entries {
+		if err := enc.Encode(e); err != nil {
+			return fmt.Errorf("encode history: %w", err)
+		}
+	}
+	if err := os.WriteFile(path, []byte(b.String()), 0o600); err != nil {
+		return fmt.Errorf("write history: %w", err)
+	}
+	return nil
+}
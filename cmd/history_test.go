@@ -0,0 +1,125 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"testing"
+)
+
+func TestHistoryPath(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", "/state")
+	if got, _ := historyPath(); got != "/state/goclip/history.jsonl" {
+		t.Errorf("with XDG_STATE_HOME: %s", got)
+	}
+	t.Setenv("XDG_STATE_HOME", "")
+	t.Setenv("HOME", "/home/u")
+	if got, _ := historyPath(); got != "/home/u/.local/state/goclip/history.jsonl" {
+		t.Errorf("without XDG_STATE_HOME: %s", got)
+	}
+}
+
+func TestHistoryRoundTrip(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	if entries, err := loadHistory(); err != nil || len(entries) != 0 {
+		t.Fatalf("missing history: %v, %v", entries, err)
+	}
+	for _, c := range []string{"first", "multi\nline\n", "third"} {
+		if err := appendHistory(c, 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	entries, err := loadHistory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 3 || entries[0].Content != "first" || entries[1].Content != "multi\nline\n" || entries[2].Content != "third" {
+		t.Errorf("entries = %+v", entries)
+	}
+	path, _ := historyPath()
+	if fi, err := os.Stat(path); err != nil || fi.Mode().Perm() != 0o600 {
+		t.Errorf("history file mode: %v, %v", fi.Mode(), err)
+	}
+}
+
+func TestHistorySkipsCorruptLines(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	path, _ := historyPath()
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	data := `{"time":"2024-03-10T12:00:00Z","content":"good"}` + "\nnot json\n" +
+		`{"time":"2024-03-10T12:01:00Z","content":"also good"}` + "\n"
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := loadHistory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries[1].Content != "also good" {
+		t.Errorf("entries = %+v", entries)
+	}
+}
+
+func TestHistoryCap(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	path, _ := historyPath()
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	var data []byte
+	for i := range maxHistoryEntries {
+		data = append(data, `{"time":"2024-03-10T12:00:00Z","content":"`+strconv.Itoa(i)+`"}`+"\n"...)
+	}
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := appendHistory("new", 0); err != nil {
+		t.Fatal(err)
+	}
+	entries, _ := loadHistory()
+	if len(entries) != maxHistoryEntries || entries[0].Content != "1" || entries[len(entries)-1].Content != "new" {
+		t.Errorf("kept %d entries from %q to %q, want %d from 1 to new",
+			len(entries), entries[0].Content, entries[len(entries)-1].Content, maxHistoryEntries)
+	}
+}
+
+func TestHistoryDedupWindow(t *testing.T) {
+	tests := []struct {
+		name   string
+		window int
+		copies []string
+		want   []string
+	}{
+		{"no window", 0, []string{"a", "a", "b", "a"}, []string{"a", "a", "b", "a"}},
+		{"last entry", 1, []string{"a", "a", "b", "a"}, []string{"a", "b", "a"}},
+		{"two back, window 1", 1, []string{"a", "b", "a"}, []string{"a", "b", "a"}},
+		{"two back, window 2", 2, []string{"a", "b", "a"}, []string{"a", "b"}},
+		{"three back, window 2", 2, []string{"a", "b", "c", "a"}, []string{"a", "b", "c", "a"}},
+		{"window larger than history", 10, []string{"a", "b", "c", "a", "b"}, []string{"a", "b", "c"}},
+		{"case matters", 5, []string{"a", "A"}, []string{"a", "A"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("XDG_STATE_HOME", t.TempDir())
+			for _, c := range tt.copies {
+				if err := appendHistory(c, tt.window); err != nil {
+					t.Fatal(err)
+				}
+			}
+			entries, err := loadHistory()
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := make([]string, len(entries))
+			for i, e := range entries {
+				got[i] = e.Content
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("history %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// shPath is sh from the original PATH, for hooks to find once
+// isolateClipboard has replaced it.
+var shPath, _ = exec.LookPath("sh")
+
+// linkShell makes sh available in the isolated PATH directory dir.
+func linkShell(t *testing.T, dir string) {
+	t.Helper()
+	if runtime.GOOS == "windows" || shPath == "" {
+		t.Skip("hooks run sh")
+	}
+	if err := os.Symlink(shPath, filepath.Join(dir, "sh")); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRunHook(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("hooks run sh")
+	}
+	out := filepath.Join(t.TempDir(), "env")
+	if err := runHook(`echo "$GOCLIP_A $GOCLIP_B" > '`+out+`'`, "GOCLIP_A=1", "GOCLIP_B=two words"); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := os.ReadFile(out); string(got) != "1 two words\n" {
+		t.Errorf("hook saw %q", got)
+	}
+	if err := runHook("exit 3"); err == nil || !strings.Contains(err.Error(), "exit 3") {
+		t.Errorf("failing hook: err = %v", err)
+	}
+}
+
+// exitStatus returns the exit status of a runGoclip error, 0 for nil.
+func exitStatus(t *testing.T, err error) int {
+	t.Helper()
+	if err == nil {
+		return 0
+	}
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		t.Fatalf("goclip didn't run: %v", err)
+	}
+	return exitErr.ExitCode()
+}
+
+func TestOnSuccess(t *testing.T) {
+	tests := []struct {
+		name       string
+		hook       string
+		args       []string
+		wantRun    bool
+		wantStatus int
+	}{
+		{"runs", "", nil, true, 0},
+		{"hook fails", "; exit 5", nil, true, 0},
+		{"hook fails, fatal", "; exit 5", []string{"--on-success-fatal"}, true, 1},
+		{"nothing copied", "", []string{"--no-clip"}, false, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := isolateClipboard(t)
+			t.Setenv("WAYLAND_DISPLAY", "wayland-0")
+			fakeHelper(t, dir, "wl-copy", 0)
+			linkShell(t, dir)
+			out := filepath.Join(dir, "hook.out")
+			hook := `echo "$GOCLIP_BYTES $GOCLIP_METHOD" > '` + out + `'` + tt.hook
+			args := append([]string{"-q", "--on-success", hook}, tt.args...)
+			_, stderr, err := runGoclip(t, "héllo\n", args...)
+			if got := exitStatus(t, err); got != tt.wantStatus {
+				t.Errorf("exit status %d, want %d\n%s", got, tt.wantStatus, stderr)
+			}
+			got, readErr := os.ReadFile(out)
+			if ran := readErr == nil; ran != tt.wantRun {
+				t.Fatalf("hook ran = %v, want %v", ran, tt.wantRun)
+			}
+			if tt.wantRun && string(got) != "7 wl-copy\n" {
+				t.Errorf("hook saw %q, want %q", got, "7 wl-copy\n")
+			}
+			if failed := tt.hook != ""; failed != strings.Contains(stderr, "on-success hook error:") {
+				t.Errorf("stderr %q", stderr)
+			}
+		})
+	}
+}
+
+func TestOnFailure(t *testing.T) {
+	tests := []struct {
+		name       string
+		copyExit   int
+		hook       string
+		wantRun    bool
+		wantStatus int
+	}{
+		{"copy fails", 1, "", true, 1},
+		{"copy and hook fail", 1, "; exit 7", true, 1},
+		{"copy works", 0, "", false, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := isolateClipboard(t)
+			t.Setenv("WAYLAND_DISPLAY", "wayland-0")
+			fakeHelper(t, dir, "wl-copy", tt.copyExit)
+			linkShell(t, dir)
+			out := filepath.Join(dir, "hook.out")
+			hook := `echo "$GOCLIP_ERROR" > '` + out + `'` + tt.hook
+			_, stderr, err := runGoclip(t, "data\n", "-q", "--no-fallback", "--on-failure", hook)
+			if got := exitStatus(t, err); got != tt.wantStatus {
+				t.Errorf("exit status %d, want %d\n%s", got, tt.wantStatus, stderr)
+			}
+			got, readErr := os.ReadFile(out)
+			if ran := readErr == nil; ran != tt.wantRun {
+				t.Fatalf("hook ran = %v, want %v", ran, tt.wantRun)
+			}
+			if tt.wantRun && !strings.Contains(string(got), "wl-copy") {
+				t.Errorf("GOCLIP_ERROR = %q, want the wl-copy failure", got)
+			}
+		})
+	}
+}
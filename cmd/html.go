@@ -0,0 +1,163 @@
+package main
+
+import (
+	"html"
+	"regexp"
+	"strings"
+)
+
+// htmlBlockTags start a new line in the extracted text.
+var htmlBlockTags = map[string]bool{
+	"address": true, "article": true, "aside": true, "blockquote": true,
+	"dd": true, "div": true, "dl": true, "dt": true, "figcaption": true,
+	"footer": true, "form": true, "h1": true, "h2": true, "h3": true,
+	"h4": true, "h5": true, "h6": true, "header": true, "hr": true,
+	"li": true, "main": true, "nav": true, "ol": true, "p": true,
+	"pre": true, "section": true, "table": true, "tr": true, "ul": true,
+}
+
+// htmlRawTextTags have content that is not text and is dropped entirely.
+var htmlRawTextTags = map[string]bool{"script": true, "style": true}
+
+var htmlSpaceRE = regexp.MustCompile(`[ \t\r\n\f]+`)
+
+// htmlText accumulates extracted text, collapsing whitespace the way a
+// browser would outside <pre>.
+type htmlText struct {
+	out []byte
+	pre int // depth of open <pre> elements
+}
+
+func (t *htmlText) atLineStart() bool {
+	return len(t.out) == 0 || t.out[len(t.out)-1] == '\n'
+}
+
+func (t *htmlText) text(s string) {
+	s = html.UnescapeString(s)
+	if t.pre == 0 {
+		s = htmlSpaceRE.ReplaceAllString(s, " ")
+		if t.atLineStart() {
+			s = strings.TrimLeft(s, " ")
+		}
+	}
+	t.out = append(t.out, s...)
+}
+
+// newline ends the current line unless already at the start of one; with
+// force (for <br>) it always adds a line break.
+func (t *htmlText) newline(force bool) {
+	for len(t.out) > 0 && t.out[len(t.out)-1] == ' ' {
+		t.out = t.out[:len(t.out)-1]
+	}
+	if force || !t.atLineStart() {
+		t.out = append(t.out, '\n')
+	}
+}
+
+// scanTag parses the tag at the start of s (which begins with '<'). It
+// returns the lower-cased tag name, whether it is a closing tag, and the
+// tag's length including the closing '>'. ok is false if s doesn't start
+// with a tag, in which case the '<' is ordinary text.
+func scanTag(s string) (name string, closing bool, n int, ok bool) {
+	i := 1
+	if i < len(s) && s[i] == '/' {
+		closing = true
+		i++
+	}
+	start := i
+	for i < len(s) && (isASCIILetter(s[i]) || (i > start && (s[i] >= '0' && s[i] <= '9' || s[i] == '-'))) {
+		i++
+	}
+	if i == start {
+		return "", false, 0, false
+	}
+	name = strings.ToLower(s[start:i])
+	// Find the closing '>', skipping over quoted attribute values.
+	var quote byte
+	for ; i < len(s); i++ {
+		switch c := s[i]; {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '>':
+			return name, closing, i + 1, true
+		}
+	}
+	return name, closing, len(s), true
+}
+
+func isASCIILetter(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// stripHTML converts HTML to readable plain text: tags are removed,
+// entities decoded, script and style content dropped, and block-level
+// elements put on their own lines.
+func stripHTML(s string) string {
+	var t htmlText
+	for len(s) > 0 {
+		lt := strings.IndexByte(s, '<')
+		if lt < 0 {
+			t.text(s)
+			break
+		}
+		t.text(s[:lt])
+		s = s[lt:]
+
+		switch {
+		case strings.HasPrefix(s, "<!--"):
+			end := strings.Index(s[4:], "-->")
+			if end < 0 {
+				return t.result()
+			}
+			s = s[4+end+3:]
+			continue
+		case strings.HasPrefix(s, "<!") || strings.HasPrefix(s, "<?"):
+			end := strings.IndexByte(s, '>')
+			if end < 0 {
+				return t.result()
+			}
+			s = s[end+1:]
+			continue
+		}
+
+		name, closing, n, ok := scanTag(s)
+		if !ok {
+			t.text("<")
+			s = s[1:]
+			continue
+		}
+		s = s[n:]
+
+		switch {
+		case htmlRawTextTags[name] && !closing:
+			end := strings.Index(strings.ToLower(s), "</"+name)
+			if end < 0 {
+				return t.result()
+			}
+			s = s[end:]
+			if _, _, n, _ := scanTag(s); n > 0 {
+				s = s[n:]
+			}
+		case name == "br":
+			t.newline(true)
+		case htmlBlockTags[name]:
+			t.newline(false)
+			if name == "pre" {
+				if closing && t.pre > 0 {
+					t.pre--
+				} else if !closing {
+					t.pre++
+				}
+			}
+		}
+	}
+	return t.result()
+}
+
+func (t *htmlText) result() string {
+	return strings.TrimRight(string(t.out), " ")
+}
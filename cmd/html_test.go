@@ -0,0 +1,31 @@
+package main
+
+import "testing"
+
+func TestStripHTML(t *testing.T) {
+	tests := []struct {
+		name, in, want string
+	}{
+		{"plain text", "no tags here", "no tags here"},
+		{"inline tags", "<b>bold</b> and <i>italic</i>", "bold and italic"},
+		{"nested tags", "<div><p>one <b>two <i>three</i></b></p><p>four</p></div>", "one two three\nfour\n"},
+		{"list", "<ul><li>a</li><li>b <em>c</em></li></ul>", "a\nb c\n"},
+		{"line breaks", "one<br>two<br/>three", "one\ntwo\nthree"},
+		{"entities", "&lt;tag&gt; &amp; &quot;q&quot; &#39;s&#39; &eacute; &#x1F642; &nbsp;", "<tag> & \"q\" 's' é 🙂 \u00a0"},
+		{"whitespace collapsed", "<p>  lots   of\n\n space\t</p>", "lots of space\n"},
+		{"pre kept", "<pre>a  b\n  c</pre>", "a  b\n  c\n"},
+		{"script dropped", "before<script>if (a < b) { alert('</p>') }</script>after", "beforeafter"},
+		{"style dropped", "<style>p { color: red }</style><p>text</p>", "text\n"},
+		{"upper-case raw text tag", "x<SCRIPT type=\"t\">var s = '<b>';</SCRIPT>y", "xy"},
+		{"unclosed script", "text<script>never closed", "text"},
+		{"comment", "a<!-- <b>hidden</b> -->b", "ab"},
+		{"doctype", "<!DOCTYPE html><p>x</p>", "x\n"},
+		{"attribute with >", `<a href="x>y" title='>'>link</a>`, "link"},
+		{"stray less-than", "1 < 2 and 3 <4", "1 < 2 and 3 <4"},
+	}
+	for _, tt := range tests {
+		if got := stripHTML(tt.in); got != tt.want {
+			t.Errorf("%s: stripHTML(%q) = %q, want %q", tt.name, tt.in, got, tt.want)
+		}
+	}
+}
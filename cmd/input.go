@@ -0,0 +1,204 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// inputKind classifies what stdin is connected to.
+type inputKind int
+
+const (
+	inputOther inputKind = iota
+	inputPipe
+	inputFile
+	inputSocket
+	inputTerminal
+)
+
+// statter is the part of *os.File needed to classify an input.
+type statter interface {
+	Stat() (os.FileInfo, error)
+	Fd() uintptr
+}
+
+// classifyInput reports what kind of file f is. Pipes, regular files and
+// sockets are all valid inputs; only an actual terminal is treated as
+// interactive. Other character devices, such as /dev/null, are inputOther.
+func classifyInput(f statter) (inputKind, error) {
+	fi, err := f.Stat()
+	if err != nil {
+		return inputOther, err
+	}
+	mode := fi.Mode()
+	switch {
+	case mode&os.ModeNamedPipe != 0:
+		return inputPipe, nil
+	case mode&os.ModeSocket != 0:
+		return inputSocket, nil
+	case mode&os.ModeCharDevice != 0:
+		if isTerminal(f.Fd()) {
+			return inputTerminal, nil
+		}
+	case mode.IsRegular():
+		return inputFile, nil
+	}
+	return inputOther, nil
+}
+
+// binarySniffLen is how much of the input looksBinary samples for control
+// characters.
+const binarySniffLen = 8 * 1024
+
+// looksBinary reports whether b appears to be binary rather than text: it
+// contains a NUL byte, or more than 10% of the sampled bytes are control
+// characters other than common whitespace and the ANSI escape.
+func looksBinary(b []byte) bool {
+	if bytes.IndexByte(b, 0) >= 0 {
+		return true
+	}
+	if len(b) > binarySniffLen {
+		b = b[:binarySniffLen]
+	}
+	control := 0
+	for _, c := range b {
+		if c < 0x20 && c != '\t' && c != '\n' && c != '\r' && c != '\f' && c != '\b' && c != 0x1b {
+			control++
+		}
+	}
+	return control*10 > len(b)
+}
+
+// readConfig describes where readInput reads from and echoes to.
+type readConfig struct {
+	in    io.Reader
+	echo  io.Writer // nil when quiet
+	limit int64
+	wait  time.Duration // keep retrying empty input for this long
+}
+
+// waitPoll is how often readInput retries empty input within cfg.wait.
+const waitPoll = 100 * time.Millisecond
+
+// readInput reads up to cfg.limit bytes and reports whether more input was
+// left unread.
+//
+// The content is always buffered in full: every transform, and the
+// clipboard write itself, needs the complete text. What differs is the
+// echo. When echoing, input is streamed to cfg.echo as it arrives (the fast
+// path), so long-running producers show output live; when quiet, it is
+// read straight into the buffer.
+//
+// If the input is empty at first, reading is retried for up to cfg.wait, for
+// producers that are slow to write, such as a file still being generated.
+func readInput(cfg readConfig) (string, bool, error) {
+	var buf bytes.Buffer
+	limited := io.LimitReader(cfg.in, cfg.limit)
+	read := func() (err error) {
+		if cfg.echo != nil {
+			_, err = io.Copy(io.MultiWriter(cfg.echo, &buf), limited)
+		} else {
+			_, err = buf.ReadFrom(limited)
+		}
+		return err
+	}
+	err := read()
+	for deadline := time.Now().Add(cfg.wait); err == nil && buf.Len() == 0 && time.Now().Before(deadline); {
+		time.Sleep(waitPoll)
+		err = read()
+	}
+	if err != nil {
+		return "", false, err
+	}
+	if f, ok := cfg.echo.(interface{ Flush() error }); ok {
+		if err := f.Flush(); err != nil {
+			return "", false, err
+		}
+	}
+	if int64(buf.Len()) < cfg.limit {
+		return buf.String(), false, nil
+	}
+	// Peek one byte past the limit to tell "exactly at the limit" from
+	// "truncated".
+	var one [1]byte
+	n, _ := io.ReadFull(cfg.in, one[:])
+	return buf.String(), n > 0, nil
+}
+
+// countingReader counts the bytes read from a named input.
+type countingReader struct {
+	r    io.Reader
+	name string
+	n    int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// sourceSummary reports the bytes read from each source and their total,
+// such as "a.txt: 200 bytes, b.txt: 350 bytes, total 550 bytes".
+func sourceSummary(sources []*countingReader, human bool) string {
+	parts := make([]string, 0, len(sources)+1)
+	var total int64
+	for _, src := range sources {
+		parts = append(parts, src.name+": "+formatBytes(int(src.n), human))
+		total += src.n
+	}
+	parts = append(parts, "total "+formatBytes(int(total), human))
+	return strings.Join(parts, ", ")
+}
+
+// openInputs returns a reader over the named files in order, with "-" (or
+// no names at all) meaning stdin, which is read from the given reader. With
+// withHeader, each source is preceded by a tail-style "==> name <==" line;
+// stdin is named by stdinLabel. It also returns the sources, which count
+// the bytes read from each, and a function that closes the opened files.
+func openInputs(stdin io.Reader, names []string, withHeader bool, stdinLabel string) (io.Reader, []*countingReader, func(), error) {
+	if len(names) == 0 {
+		names = []string{"-"}
+	}
+	var readers []io.Reader
+	var sources []*countingReader
+	var files []*os.File
+	closeAll := func() {
+		for _, f := range files {
+			f.Close()
+		}
+	}
+	for i, name := range names {
+		r := stdin
+		label := stdinLabel
+		if name != "-" {
+			f, err := os.Open(name)
+			if err != nil {
+				closeAll()
+				return nil, nil, nil, err
+			}
+			files = append(files, f)
+			r, label = f, name
+		}
+		if withHeader {
+			sep := ""
+			if i > 0 {
+				sep = "\n"
+			}
+			readers = append(readers, bytes.NewReader(fmt.Appendf(nil, "%s==> %s <==\n", sep, label)))
+		}
+		src := &countingReader{r: r, name: label}
+		sources = append(sources, src)
+		readers = append(readers, src)
+	}
+	if len(readers) == 1 {
+		// Read it directly: a MultiReader stops at the first EOF, which
+		// would defeat --wait.
+		return readers[0], sources, closeAll, nil
+	}
+	return io.MultiReader(readers...), sources, closeAll, nil
+}
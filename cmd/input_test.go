@@ -0,0 +1,397 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/fs"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+)
+
+// fakeInput is a statter reporting a fixed mode, with the descriptor of a
+// real file for the terminal check.
+type fakeInput struct {
+	mode fs.FileMode
+	fd   uintptr
+}
+
+func (f fakeInput) Stat() (os.FileInfo, error) { return fakeInfo{f.mode}, nil }
+func (f fakeInput) Fd() uintptr                { return f.fd }
+
+type fakeInfo struct{ mode fs.FileMode }
+
+func (i fakeInfo) Name() string       { return "stdin" }
+func (i fakeInfo) Size() int64        { return 0 }
+func (i fakeInfo) Mode() fs.FileMode  { return i.mode }
+func (i fakeInfo) ModTime() time.Time { return time.Time{} }
+func (i fakeInfo) IsDir() bool        { return false }
+func (i fakeInfo) Sys() any           { return nil }
+
+func TestClassifyInputModes(t *testing.T) {
+	devNull, err := os.Open(os.DevNull)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer devNull.Close()
+
+	tests := []struct {
+		name string
+		mode fs.FileMode
+		want inputKind
+	}{
+		{"named pipe", fs.ModeNamedPipe, inputPipe},
+		{"socket", fs.ModeSocket, inputSocket},
+		{"regular file", 0, inputFile},
+		{"char device that isn't a terminal", fs.ModeDevice | fs.ModeCharDevice, inputOther},
+		{"directory", fs.ModeDir, inputOther},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := classifyInput(fakeInput{tt.mode, devNull.Fd()})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("classifyInput = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClassifyInputTerminal(t *testing.T) {
+	tty, err := os.Open("/dev/tty")
+	if err != nil {
+		t.Skip("no controlling terminal:", err)
+	}
+	defer tty.Close()
+	if got, err := classifyInput(tty); err != nil || got != inputTerminal {
+		t.Errorf("classifyInput(/dev/tty) = %v, %v; want inputTerminal", got, err)
+	}
+}
+
+func TestClassifyInput(t *testing.T) {
+	devNull, err := os.Open(os.DevNull)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer devNull.Close()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	defer w.Close()
+	file, err := os.CreateTemp(t.TempDir(), "in")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	sockPath := filepath.Join(t.TempDir(), "s")
+	ln, err := net.Listen("unix", sockPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	conn, err := net.Dial("unix", sockPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	sock, err := conn.(*net.UnixConn).File()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sock.Close()
+
+	tests := []struct {
+		name string
+		f    *os.File
+		want inputKind
+	}{
+		{"pipe", r, inputPipe},
+		{"regular file", file, inputFile},
+		{"socket", sock, inputSocket},
+		{"null device is not a terminal", devNull, inputOther},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := classifyInput(tt.f)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("classifyInput = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLooksBinary(t *testing.T) {
+	tests := []struct {
+		name string
+		in   []byte
+		want bool
+	}{
+		{"text", []byte("hello\nworld\n"), false},
+		{"empty", nil, false},
+		{"utf-8", []byte("héllo 日本語 🙂\n"), false},
+		{"whitespace and escapes", []byte("a\tb\r\n\f\b\x1b[31mred\x1b[0m\n"), false},
+		{"NUL byte", []byte("text\x00more"), true},
+		{"NUL after the sniffed prefix", append(bytes.Repeat([]byte("a"), binarySniffLen+10), 0), true},
+		{"mostly control bytes", []byte("\x01\x02\x03\x04abc"), true},
+		{"a few control bytes", append(bytes.Repeat([]byte("a"), 100), 0x01, 0x02), false},
+		{"PNG header", []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"), true},
+	}
+	for _, tt := range tests {
+		if got := looksBinary(tt.in); got != tt.want {
+			t.Errorf("%s: looksBinary = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+// lateReader is empty for its first empties reads, like a file that is
+// still being generated, then returns data.
+type lateReader struct {
+	empties int
+	data    *strings.Reader
+}
+
+func (l *lateReader) Read(p []byte) (int, error) {
+	if l.empties > 0 {
+		l.empties--
+		return 0, io.EOF
+	}
+	return l.data.Read(p)
+}
+
+func TestReadInput(t *testing.T) {
+	tests := []struct {
+		name          string
+		in            string
+		echo          bool
+		limit         int64
+		want          string
+		wantTruncated bool
+	}{
+		{"buffered", "hello\nworld\n", false, 100, "hello\nworld\n", false},
+		{"streamed", "hello\nworld\n", true, 100, "hello\nworld\n", false},
+		{"buffered, exactly at the limit", "12345", false, 5, "12345", false},
+		{"streamed, exactly at the limit", "12345", true, 5, "12345", false},
+		{"buffered, truncated", "1234567", false, 5, "12345", true},
+		{"streamed, truncated", "1234567", true, 5, "12345", true},
+		{"empty", "", false, 5, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := readConfig{in: strings.NewReader(tt.in), limit: tt.limit}
+			var echo bytes.Buffer
+			if tt.echo {
+				cfg.echo = &echo
+			}
+			got, truncated, err := readInput(cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want || truncated != tt.wantTruncated {
+				t.Errorf("got %q, %v, want %q, %v", got, truncated, tt.want, tt.wantTruncated)
+			}
+			wantEcho := ""
+			if tt.echo {
+				wantEcho = tt.want
+			}
+			if echo.String() != wantEcho {
+				t.Errorf("echoed %q, want %q", echo.String(), wantEcho)
+			}
+		})
+	}
+}
+
+func TestReadInputWait(t *testing.T) {
+	tests := []struct {
+		name    string
+		empties int
+		wait    time.Duration
+		want    string
+	}{
+		{"no wait", 2, 0, ""},
+		{"data arrives within the wait", 2, 5 * time.Second, "late"},
+		{"wait runs out", 1000, 3 * waitPoll, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			in := &lateReader{empties: tt.empties, data: strings.NewReader("late")}
+			got, _, err := readInput(readConfig{in: in, limit: 100, wait: tt.wait})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadInputError(t *testing.T) {
+	in := io.MultiReader(strings.NewReader("partial"), iotest.ErrReader(errors.New("read failed")))
+	for _, echo := range []io.Writer{nil, io.Discard} {
+		if _, _, err := readInput(readConfig{in: in, echo: echo, limit: 100}); err == nil {
+			t.Errorf("echo %v: want the read error", echo != nil)
+		}
+	}
+}
+
+func TestOpenInputs(t *testing.T) {
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a.txt")
+	b := filepath.Join(dir, "b.txt")
+	os.WriteFile(a, []byte("alpha\n"), 0o600)
+	os.WriteFile(b, []byte("beta\n"), 0o600)
+	tests := []struct {
+		name       string
+		names      []string
+		withHeader bool
+		label      string
+		want       string
+		wantCounts []int64
+	}{
+		{"stdin", nil, false, "stdin", "piped\n", []int64{6}},
+		{"files", []string{a, b}, false, "stdin", "alpha\nbeta\n", []int64{6, 5}},
+		{"files with headers", []string{a, b}, true, "stdin",
+			"==> " + a + " <==\nalpha\n\n==> " + b + " <==\nbeta\n", []int64{6, 5}},
+		{"stdin with a custom label", []string{"-"}, true, "build log",
+			"==> build log <==\npiped\n", []int64{6}},
+		{"no names with a label", nil, true, "build log",
+			"==> build log <==\npiped\n", []int64{6}},
+		{"stdin between files", []string{a, "-", b}, true, "(piped)",
+			"==> " + a + " <==\nalpha\n\n==> (piped) <==\npiped\n\n==> " + b + " <==\nbeta\n", []int64{6, 6, 5}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, sources, closeAll, err := openInputs(strings.NewReader("piped\n"), tt.names, tt.withHeader, tt.label)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer closeAll()
+			got, err := io.ReadAll(r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("read %q, want %q", got, tt.want)
+			}
+			counts := make([]int64, len(sources))
+			for i, src := range sources {
+				counts[i] = src.n
+			}
+			if !slices.Equal(counts, tt.wantCounts) {
+				t.Errorf("counted %v, want %v", counts, tt.wantCounts)
+			}
+		})
+	}
+
+	if _, _, _, err := openInputs(strings.NewReader(""), []string{a, filepath.Join(dir, "missing")}, true, "stdin"); err == nil {
+		t.Error("a missing file: want an error")
+	}
+}
+
+// TestReadInputDelayedFile reads a file that is empty when opened and
+// written a little later, as --wait is for.
+func TestReadInputDelayedFile(t *testing.T) {
+	tests := []struct {
+		name  string
+		delay time.Duration
+		wait  time.Duration
+		want  string
+	}{
+		{"written within the wait", 2 * waitPoll, 10 * waitPoll, "generated\n"},
+		{"written too late", 6 * waitPoll, 2 * waitPoll, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "report.txt")
+			f, err := os.Create(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+			in, err := os.Open(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer in.Close()
+			written := make(chan struct{})
+			time.AfterFunc(tt.delay, func() {
+				f.WriteString("generated\n")
+				close(written)
+			})
+			start := time.Now()
+			got, _, err := readInput(readConfig{in: in, limit: 100, wait: tt.wait})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+			if elapsed := time.Since(start); tt.want == "" && elapsed < tt.wait {
+				t.Errorf("gave up after %v, before the %v wait", elapsed, tt.wait)
+			}
+			<-written
+		})
+	}
+}
+
+func TestSourceSummary(t *testing.T) {
+	src := func(name string, n int64) *countingReader { return &countingReader{name: name, n: n} }
+	tests := []struct {
+		sources []*countingReader
+		human   bool
+		want    string
+	}{
+		{[]*countingReader{src("a.txt", 200), src("b.txt", 350)}, false, "a.txt: 200 bytes, b.txt: 350 bytes, total 550 bytes"},
+		{[]*countingReader{src("a.txt", 1), src("stdin", 0)}, false, "a.txt: 1 byte, stdin: 0 bytes, total 1 byte"},
+		{[]*countingReader{src("big", 3<<10), src("small", 10)}, true, "big: 3.0 KB, small: 10 bytes, total 3.0 KB"},
+	}
+	for _, tt := range tests {
+		if got := sourceSummary(tt.sources, tt.human); got != tt.want {
+			t.Errorf("sourceSummary = %q, want %q", got, tt.want)
+		}
+	}
+}
+
+// TestSourceSummaryReported checks the per-file summary is printed for
+// several inputs, and not for one or with -q.
+func TestSourceSummaryReported(t *testing.T) {
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a.txt")
+	b := filepath.Join(dir, "b.txt")
+	os.WriteFile(a, []byte("alpha\n"), 0o600)
+	os.WriteFile(b, []byte("beta\n"), 0o600)
+	summary := a + ": 6 bytes, " + b + ": 5 bytes, total 11 bytes"
+	tests := []struct {
+		name string
+		args []string
+		want bool
+	}{
+		{"two files", []string{a, b}, true},
+		{"one file", []string{a}, false},
+		{"quiet", []string{"-q", a, b}, false},
+	}
+	for _, tt := range tests {
+		args := append([]string{"--no-clip"}, tt.args...)
+		_, stderr, err := runGoclip(t, "", args...)
+		if err != nil {
+			t.Fatalf("%s: %v\n%s", tt.name, err, stderr)
+		}
+		if got := strings.Contains(stderr, summary); got != tt.want {
+			t.Errorf("%s: summary printed = %v, want %v\n%s", tt.name, got, tt.want, stderr)
+		}
+	}
+}
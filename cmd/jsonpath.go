@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// jsonStep is one step of a --json-field path: an object key, or an array
+// index when key is empty.
+type jsonStep struct {
+	key   string
+	index int
+}
+
+// parseJSONPath parses a minimal jq-style path such as ".data.url" or
+// ".items[0].name". "." alone selects the whole document.
+func parseJSONPath(path string) ([]jsonStep, error) {
+	if !strings.HasPrefix(path, ".") && !strings.HasPrefix(path, "[") {
+		return nil, fmt.Errorf("path %q must start with '.' or '['", path)
+	}
+	var steps []jsonStep
+	for rest := path; rest != ""; {
+		switch rest[0] {
+		case '.':
+			rest = rest[1:]
+			end := strings.IndexAny(rest, ".[")
+			if end < 0 {
+				end = len(rest)
+			}
+			if end == 0 {
+				if rest == "" && len(steps) == 0 {
+					return nil, nil // "."
+				}
+				if rest == "" || rest[0] == '.' {
+					return nil, fmt.Errorf("path %q: empty key", path)
+				}
+				continue // ".[0]"
+			}
+			steps = append(steps, jsonStep{key: rest[:end]})
+			rest = rest[end:]
+		case '[':
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("path %q: missing ']'", path)
+			}
+			i, err := strconv.Atoi(rest[1:end])
+			if err != nil {
+				return nil, fmt.Errorf("path %q: invalid index %q", path, rest[1:end])
+			}
+			steps = append(steps, jsonStep{index: i})
+			rest = rest[end+1:]
+		default:
+			return nil, fmt.Errorf("path %q: unexpected %q", path, rest[0])
+		}
+	}
+	return steps, nil
+}
+
+// extractJSON parses s as JSON and returns the value at path. Strings are
+// returned without quotes, arrays as one element per line and anything
+// else as compact JSON. A negative index counts from the end of an array.
+func extractJSON(s string, path []jsonStep) (string, error) {
+	dec := json.NewDecoder(strings.NewReader(s))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return "", fmt.Errorf("parse JSON: %w", err)
+	}
+	where := ""
+	for _, st := range path {
+		if st.key != "" {
+			where += "." + st.key
+			obj, ok := v.(map[string]any)
+			if !ok {
+				return "", fmt.Errorf("%s: not an object", where)
+			}
+			if v, ok = obj[st.key]; !ok {
+				return "", fmt.Errorf("%s: no such key", where)
+			}
+			continue
+		}
+		where += fmt.Sprintf("[%d]", st.index)
+		arr, ok := v.([]any)
+		if !ok {
+			return "", fmt.Errorf("%s: not an array", where)
+		}
+		i := st.index
+		if i < 0 {
+			i += len(arr)
+		}
+		if i < 0 || i >= len(arr) {
+			return "", fmt.Errorf("%s: index out of range (length %d)", where, len(arr))
+		}
+		v = arr[i]
+	}
+
+	if arr, ok := v.([]any); ok {
+		lines := make([]string, len(arr))
+		for i, el := range arr {
+			lines[i] = jsonText(el)
+		}
+		return strings.Join(lines, "\n"), nil
+	}
+	return jsonText(v), nil
+}
+
+// jsonText renders a decoded JSON value: strings as-is, anything else as
+// compact JSON.
+func jsonText(v any) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	_ = enc.Encode(v)
+	return strings.TrimSuffix(buf.String(), "\n")
+}
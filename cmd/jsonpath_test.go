@@ -0,0 +1,99 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseJSONPath(t *testing.T) {
+	tests := []struct {
+		path    string
+		want    []jsonStep
+		wantErr string
+	}{
+		{".", nil, ""},
+		{".data.url", []jsonStep{{key: "data"}, {key: "url"}}, ""},
+		{".items[0].name", []jsonStep{{key: "items"}, {index: 0}, {key: "name"}}, ""},
+		{"[2]", []jsonStep{{index: 2}}, ""},
+		{".[1]", []jsonStep{{index: 1}}, ""},
+		{".a[-1]", []jsonStep{{key: "a"}, {index: -1}}, ""},
+		{".m[0][1]", []jsonStep{{key: "m"}, {index: 0}, {index: 1}}, ""},
+		{"data", nil, "must start with"},
+		{".a..b", nil, "empty key"},
+		{".a.", nil, "empty key"},
+		{".a[0", nil, "missing ']'"},
+		{".a[x]", nil, "invalid index"},
+		{".a[0]b", nil, "unexpected"},
+	}
+	for _, tt := range tests {
+		got, err := parseJSONPath(tt.path)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("parseJSONPath(%q) err = %v, want %q", tt.path, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || !equalSteps(got, tt.want) {
+			t.Errorf("parseJSONPath(%q) = %v, %v, want %v", tt.path, got, err, tt.want)
+		}
+	}
+}
+
+func equalSteps(a, b []jsonStep) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestExtractJSON(t *testing.T) {
+	const doc = `{
+		"data": {"url": "https://example.com/x?a=1&b=2", "count": 3, "ok": true, "none": null},
+		"items": [{"name": "first"}, {"name": "second", "tags": ["a", "b"]}],
+		"big": 12345678901234567890,
+		"nested": {"deep": {"list": [1, "two", {"three": 3}]}}
+	}`
+	tests := []struct {
+		path, want, wantErr string
+	}{
+		{".data.url", "https://example.com/x?a=1&b=2", ""},
+		{".data.count", "3", ""},
+		{".data.ok", "true", ""},
+		{".data.none", "null", ""},
+		{".items[0].name", "first", ""},
+		{".items[-1].name", "second", ""},
+		{".items[1].tags", "a\nb", ""},
+		{".nested.deep.list", "1\ntwo\n{\"three\":3}", ""},
+		{".nested.deep", `{"list":[1,"two",{"three":3}]}`, ""},
+		{".big", "12345678901234567890", ""},
+		{".data.missing", "", ".data.missing: no such key"},
+		{".items[5]", "", ".items[5]: index out of range (length 2)"},
+		{".items.name", "", ".items.name: not an object"},
+		{".data[0]", "", ".data[0]: not an array"},
+	}
+	for _, tt := range tests {
+		path, err := parseJSONPath(tt.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := extractJSON(doc, path)
+		if tt.wantErr != "" {
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("%s: err = %v, want %q", tt.path, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("%s = %q, %v, want %q", tt.path, got, err, tt.want)
+		}
+	}
+
+	if _, err := extractJSON("not json", nil); err == nil || !strings.Contains(err.Error(), "parse JSON") {
+		t.Errorf("invalid JSON: err = %v", err)
+	}
+}
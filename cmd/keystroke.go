@@ -0,0 +1,23 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+)
+
+// pasteKeystrokeCmd returns a command that presses Ctrl+V in the focused
+// window: wtype on Wayland, xdotool on X11. The bool is false if neither
+// is available for the current session.
+func pasteKeystrokeCmd() (string, []string, bool) {
+	if os.Getenv("WAYLAND_DISPLAY") != "" {
+		if p, err := exec.LookPath("wtype"); err == nil {
+			return p, []string{"-M", "ctrl", "v", "-m", "ctrl"}, true
+		}
+	}
+	if os.Getenv("DISPLAY") != "" {
+		if p, err := exec.LookPath("xdotool"); err == nil {
+			return p, []string{"key", "--clearmodifiers", "ctrl+v"}, true
+		}
+	}
+	return "", nil, false
+}
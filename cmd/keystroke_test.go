@@ -0,0 +1,87 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPasteKeystrokeCmd(t *testing.T) {
+	tests := []struct {
+		name    string
+		helpers []string
+		wayland bool
+		x11     bool
+		want    string // helper and arguments, "" for none
+	}{
+		{"wayland", []string{"wtype", "xdotool"}, true, true, "wtype -M ctrl v -m ctrl"},
+		{"x11", []string{"wtype", "xdotool"}, false, true, "xdotool key --clearmodifiers ctrl+v"},
+		{"xwayland without wtype", []string{"xdotool"}, true, true, "xdotool key --clearmodifiers ctrl+v"},
+		{"wayland without wtype", []string{"xdotool"}, true, false, ""},
+		{"no display", []string{"wtype", "xdotool"}, false, false, ""},
+		{"nothing installed", nil, true, true, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := isolateClipboard(t)
+			if tt.wayland {
+				t.Setenv("WAYLAND_DISPLAY", "wayland-0")
+			}
+			if tt.x11 {
+				t.Setenv("DISPLAY", ":0")
+			}
+			for _, h := range tt.helpers {
+				fakeHelper(t, dir, h, 0)
+			}
+			bin, args, ok := pasteKeystrokeCmd()
+			got := ""
+			if ok {
+				got = strings.Join(append([]string{filepath.Base(bin)}, args...), " ")
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPasteInto(t *testing.T) {
+	tests := []struct {
+		name        string
+		wtype       int // exit status; -1 means not installed
+		args        []string
+		wantRun     bool
+		wantMessage string
+	}{
+		{"pastes", 0, nil, true, ""},
+		{"keystroke fails", 1, nil, true, "paste-into error:"},
+		{"no keystroke tool", -1, nil, false, "--paste-into needs xdotool (X11) or wtype (Wayland)"},
+		{"nothing copied", 0, []string{"--no-clip"}, false, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := isolateClipboard(t)
+			t.Setenv("WAYLAND_DISPLAY", "wayland-0")
+			fakeHelper(t, dir, "wl-copy", 0)
+			if tt.wtype >= 0 {
+				fakeHelper(t, dir, "wtype", tt.wtype)
+			}
+			args := append([]string{"-q", "--no-fallback", "--paste-into"}, tt.args...)
+			_, stderr, err := runGoclip(t, "data\n", args...)
+			if err != nil {
+				t.Fatalf("goclip: %v\n%s", err, stderr)
+			}
+			_, statErr := os.Stat(filepath.Join(dir, "wtype.args"))
+			if ran := statErr == nil; ran != tt.wantRun {
+				t.Fatalf("wtype ran = %v, want %v", ran, tt.wantRun)
+			}
+			if tt.wantRun && helperArgs(dir, "wtype") != "-M ctrl v -m ctrl" {
+				t.Errorf("wtype args %q", helperArgs(dir, "wtype"))
+			}
+			if tt.wantMessage != "" && !strings.Contains(stderr, tt.wantMessage) {
+				t.Errorf("stderr %q, want %q", stderr, tt.wantMessage)
+			}
+		})
+	}
+}
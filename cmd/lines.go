@@ -0,0 +1,290 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// splitLines breaks s into lines without their trailing newline. The bool
+// reports whether s ended with a newline so joinLines can restore it.
+func splitLines(s string) ([]string, bool) {
+	if s == "" {
+		return nil, false
+	}
+	trailingNL := strings.HasSuffix(s, "\n")
+	if trailingNL {
+		s = s[:len(s)-1]
+	}
+	return strings.Split(s, "\n"), trailingNL
+}
+
+// joinLines is the inverse of splitLines.
+func joinLines(lines []string, trailingNL bool) string {
+	if len(lines) == 0 {
+		return ""
+	}
+	out := strings.Join(lines, "\n")
+	if trailingNL {
+		out += "\n"
+	}
+	return out
+}
+
+// mapLines applies fn to every line of s.
+func mapLines(s string, fn func(string) string) string {
+	lines, trailingNL := splitLines(s)
+	for i, line := range lines {
+		lines[i] = fn(line)
+	}
+	return joinLines(lines, trailingNL)
+}
+
+// filterLines keeps the lines of s for which keep returns true. It also
+// returns how many lines were dropped.
+func filterLines(s string, keep func(string) bool) (string, int) {
+	lines, trailingNL := splitLines(s)
+	kept := lines[:0]
+	for _, line := range lines {
+		if keep(line) {
+			kept = append(kept, line)
+		}
+	}
+	return joinLines(kept, trailingNL && len(kept) > 0), len(lines) - len(kept)
+}
+
+// grepLines keeps only the lines matching re and returns how many matched.
+func grepLines(s string, re *regexp.Regexp) (string, int) {
+	matched := 0
+	out, _ := filterLines(s, func(line string) bool {
+		if re.MatchString(line) {
+			matched++
+			return true
+		}
+		return false
+	})
+	return out, matched
+}
+
+// grepVLines drops the lines matching re and returns how many were dropped.
+func grepVLines(s string, re *regexp.Regexp) (string, int) {
+	return filterLines(s, func(line string) bool { return !re.MatchString(line) })
+}
+
+// absPath resolves line as a path relative to the working directory. Blank
+// lines and lines that cannot be a path are returned unchanged.
+func absPath(line string) string {
+	if strings.TrimSpace(line) == "" || strings.ContainsRune(line, 0) {
+		return line
+	}
+	p, err := filepath.Abs(line)
+	if err != nil {
+		return line
+	}
+	return p
+}
+
+// dedent removes the longest leading-whitespace prefix shared by all
+// non-blank lines. Tabs and spaces are compared literally, so a tab never
+// matches a run of spaces. Whitespace-only lines are emptied.
+func dedent(s string) string {
+	lines, trailingNL := splitLines(s)
+	prefix, found := "", false
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		indent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+		if !found {
+			prefix, found = indent, true
+			continue
+		}
+		n := 0
+		for n < len(prefix) && n < len(indent) && prefix[n] == indent[n] {
+			n++
+		}
+		prefix = prefix[:n]
+	}
+	for i, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			lines[i] = ""
+			continue
+		}
+		lines[i] = strings.TrimPrefix(line, prefix)
+	}
+	return joinLines(lines, trailingNL)
+}
+
+// commentPrefixes splits a --strip-comments value into its comma-separated
+// prefixes, ignoring empty ones.
+func commentPrefixes(s string) []string {
+	var prefixes []string
+	for p := range strings.SplitSeq(s, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			prefixes = append(prefixes, p)
+		}
+	}
+	return prefixes
+}
+
+// isCommentLine reports whether line, after any indentation, starts with
+// one of prefixes. Comments after code on the same line don't count.
+func isCommentLine(line string, prefixes []string) bool {
+	body := strings.TrimLeft(line, " \t")
+	for _, p := range prefixes {
+		if strings.HasPrefix(body, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// squeezeSpaces collapses each run of spaces and tabs in line to a single
+// space. Indentation is left alone, so dedent and code layout still work.
+func squeezeSpaces(line string) string {
+	body := strings.TrimLeft(line, " \t")
+	indent := line[:len(line)-len(body)]
+	var b strings.Builder
+	b.WriteString(indent)
+	inRun := false
+	for _, r := range body {
+		if r == ' ' || r == '\t' {
+			inRun = true
+			continue
+		}
+		if inRun {
+			b.WriteByte(' ')
+			inRun = false
+		}
+		b.WriteRune(r)
+	}
+	if inRun {
+		b.WriteByte(' ')
+	}
+	return b.String()
+}
+
+// limitLineLength handles lines wider than n display columns: they are
+// dropped, or cut down to n columns when truncate is set. Lines of exactly n
+// columns are kept as is.
+func limitLineLength(s string, n int, truncate bool) string {
+	if truncate {
+		return mapLines(s, func(line string) string {
+			return headWidth(line, n)
+		})
+	}
+	out, _ := filterLines(s, func(line string) bool {
+		return displayWidth(line) <= n
+	})
+	return out
+}
+
+// truncateBytes shortens s to at most n bytes without splitting a UTF-8
+// sequence.
+func truncateBytes(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+// lastLineContains reports whether the last non-blank line of s contains
+// marker.
+func lastLineContains(s, marker string) bool {
+	lines, _ := splitLines(s)
+	for i := len(lines) - 1; i >= 0; i-- {
+		if strings.TrimSpace(lines[i]) != "" {
+			return strings.Contains(lines[i], marker)
+		}
+	}
+	return false
+}
+
+// firstMatch returns the first line of s matching re.
+func firstMatch(s string, re *regexp.Regexp) (string, bool) {
+	lines, _ := splitLines(s)
+	for _, line := range lines {
+		if re.MatchString(line) {
+			return line, true
+		}
+	}
+	return "", false
+}
+
+// lineSpan returns the lines of s selected by sp, which counts lines from
+// 0. Lines keep their terminating newlines.
+func lineSpan(s string, sp span) string {
+	lines, trailingNL := splitLines(s)
+	lo, hi := sp.clamp(len(lines))
+	return joinLines(lines[lo:hi], trailingNL || hi < len(lines))
+}
+
+// uniqLines collapses runs of identical lines into one, like uniq; with
+// ignoreCase, lines differing only in case count as identical and the first
+// is kept.
+func uniqLines(s string, ignoreCase bool) string {
+	lines, trailingNL := splitLines(s)
+	lines = slices.CompactFunc(lines, func(a, b string) bool {
+		if ignoreCase {
+			return strings.EqualFold(a, b)
+		}
+		return a == b
+	})
+	return joinLines(lines, trailingNL)
+}
+
+// numberLines prefixes each line of s with its line number, counting from
+// start and right-aligned to the widest number.
+func numberLines(s string, start int) string {
+	lines, trailingNL := splitLines(s)
+	width := max(len(strconv.Itoa(start)), len(strconv.Itoa(start+len(lines)-1)))
+	for i, line := range lines {
+		lines[i] = fmt.Sprintf("%*d  %s", width, start+i, line)
+	}
+	return joinLines(lines, trailingNL)
+}
+
+// joinWith joins the lines of s with sep, dropping carriage returns and,
+// if skipBlank is set, blank lines.
+func joinWith(s, sep string, skipBlank bool) string {
+	lines, _ := splitLines(s)
+	kept := lines[:0]
+	for _, line := range lines {
+		line = strings.TrimSuffix(line, "\r")
+		if skipBlank && strings.TrimSpace(line) == "" {
+			continue
+		}
+		kept = append(kept, line)
+	}
+	return strings.Join(kept, sep)
+}
+
+// expandTabs replaces the tabs in each line of s with spaces up to the next
+// multiple of width display columns, like expand(1).
+func expandTabs(s string, width int) string {
+	return mapLines(s, func(line string) string {
+		if !strings.Contains(line, "\t") {
+			return line
+		}
+		var b strings.Builder
+		col := 0
+		for _, r := range line {
+			if r == '\t' {
+				n := width - col%width
+				b.WriteString(strings.Repeat(" ", n))
+				col += n
+				continue
+			}
+			b.WriteRune(r)
+			col += runeWidth(r)
+		}
+		return b.String()
+	})
+}
@@ -0,0 +1,353 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestJoinWith(t *testing.T) {
+	tests := []struct {
+		name      string
+		in, sep   string
+		skipBlank bool
+		want      string
+	}{
+		{"space", "a\nb\nc\n", " ", false, "a b c"},
+		{"comma", "a\nb\nc", ",", false, "a,b,c"},
+		{"comma and space", "x\ny\n", ", ", false, "x, y"},
+		{"blank lines kept", "a\n\nb\n", ",", false, "a,,b"},
+		{"blank lines skipped", "a\n\n  \nb\n", ",", true, "a,b"},
+		{"CRLF", "a\r\nb\r\n", " ", false, "a b"},
+		{"single line", "only\n", ",", false, "only"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := joinWith(tt.in, tt.sep, tt.skipBlank); got != tt.want {
+				t.Errorf("joinWith(%q, %q, %v) = %q, want %q", tt.in, tt.sep, tt.skipBlank, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCommentPrefixes(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []string
+	}{
+		{"#", []string{"#"}},
+		{"//,;", []string{"//", ";"}},
+		{" # , // ", []string{"#", "//"}},
+		{"#,,", []string{"#"}},
+		{"", nil},
+	}
+	for _, tt := range tests {
+		if got := commentPrefixes(tt.in); !slices.Equal(got, tt.want) {
+			t.Errorf("commentPrefixes(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestIsCommentLine(t *testing.T) {
+	tests := []struct {
+		line     string
+		prefixes []string
+		want     bool
+	}{
+		{"# comment", []string{"#"}, true},
+		{"    # indented", []string{"#"}, true},
+		{"\t\t# tabbed", []string{"#"}, true},
+		{"key = value # inline", []string{"#"}, false},
+		{"", []string{"#"}, false},
+		{"// go", []string{"//", ";"}, true},
+		{"  ; ini", []string{"//", ";"}, true},
+		{"# hash", []string{"//", ";"}, false},
+		{"/ not quite", []string{"//"}, false},
+		{"code(); // trailing", []string{"//", ";"}, false},
+	}
+	for _, tt := range tests {
+		if got := isCommentLine(tt.line, tt.prefixes); got != tt.want {
+			t.Errorf("isCommentLine(%q, %q) = %v, want %v", tt.line, tt.prefixes, got, tt.want)
+		}
+	}
+}
+
+func TestGrepCounts(t *testing.T) {
+	tests := []struct {
+		name        string
+		in, re      string
+		wantGrep    string
+		wantMatched int
+		wantGrepV   string
+		wantDropped int
+	}{
+		{"some match", "error: a\ninfo: b\nerror: c\n", "^error", "error: a\nerror: c\n", 2, "info: b\n", 2},
+		{"none match", "a\nb\n", "z", "", 0, "a\nb\n", 0},
+		{"all match", "a1\na2", `\d`, "a1\na2", 2, "", 2},
+		{"unterminated last line", "x\ny", "y", "y", 1, "x", 1},
+		{"empty input", "", ".", "", 0, "", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			re := regexp.MustCompile(tt.re)
+			got, n := grepLines(tt.in, re)
+			if got != tt.wantGrep || n != tt.wantMatched {
+				t.Errorf("grepLines = %q, %d; want %q, %d", got, n, tt.wantGrep, tt.wantMatched)
+			}
+			got, n = grepVLines(tt.in, re)
+			if got != tt.wantGrepV || n != tt.wantDropped {
+				t.Errorf("grepVLines = %q, %d; want %q, %d", got, n, tt.wantGrepV, tt.wantDropped)
+			}
+		})
+	}
+}
+
+func TestSplitJoinLines(t *testing.T) {
+	for _, s := range []string{"", "a", "a\n", "a\nb", "a\nb\n", "\n", "\n\n"} {
+		lines, nl := splitLines(s)
+		if got := joinLines(lines, nl); got != s {
+			t.Errorf("joinLines(splitLines(%q)) = %q", s, got)
+		}
+	}
+}
+
+func TestAbsPath(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		in, want string
+	}{
+		{"main.go", filepath.Join(wd, "main.go")},
+		{"./sub/../x.txt", filepath.Join(wd, "x.txt")},
+		{"../y", filepath.Join(filepath.Dir(wd), "y")},
+		{"/already/absolute", "/already/absolute"},
+		{"/not/../clean", "/clean"},
+		{"", ""},
+		{"   ", "   "},
+		{"bad\x00path", "bad\x00path"},
+	}
+	for _, tt := range tests {
+		if got := absPath(tt.in); got != tt.want {
+			t.Errorf("absPath(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestDedent(t *testing.T) {
+	tests := []struct {
+		name, in, want string
+	}{
+		{"spaces", "    a\n      b\n    c\n", "a\n  b\nc\n"},
+		{"tabs", "\t\tif x {\n\t\t\ty()\n\t\t}", "if x {\n\ty()\n}"},
+		{"mixed shares only the common run", "\t  a\n\t b\n", " a\nb\n"},
+		{"tab never matches spaces", "\ta\n    b\n", "\ta\n    b\n"},
+		{"no common prefix", "a\n  b\n", "a\n  b\n"},
+		{"blank lines ignored and emptied", "  a\n\n   \n  b\n", "a\n\n\nb\n"},
+		{"all blank", "  \n\t\n", "\n\n"},
+		{"empty", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := dedent(tt.in); got != tt.want {
+				t.Errorf("dedent(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLimitLineLength(t *testing.T) {
+	in := "short\nexactly10!\nthis one is too long\n"
+	tests := []struct {
+		name     string
+		in       string
+		n        int
+		truncate bool
+		want     string
+	}{
+		{"drop", in, 10, false, "short\nexactly10!\n"},
+		{"truncate", in, 10, true, "short\nexactly10!\nthis one i\n"},
+		{"all kept", in, 100, false, in},
+		{"all dropped", "long\nlonger\n", 2, false, ""},
+		{"wide characters count two columns", "日本語\nab\n", 4, false, "ab\n"},
+		{"truncate wide characters", "日本語\n", 5, true, "日本\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := limitLineLength(tt.in, tt.n, tt.truncate); got != tt.want {
+				t.Errorf("limitLineLength(%q, %d, %v) = %q, want %q", tt.in, tt.n, tt.truncate, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLastLineContains(t *testing.T) {
+	tests := []struct {
+		in, marker string
+		want       bool
+	}{
+		{"output\nBUILD OK\n", "BUILD OK", true},
+		{"output\nresult: BUILD OK (3s)", "BUILD OK", true},
+		{"BUILD OK\nthen it failed\n", "BUILD OK", false},
+		{"output\nBUILD OK\n\n  \n", "BUILD OK", true},
+		{"output only\n", "BUILD OK", false},
+		{"", "BUILD OK", false},
+		{"\n\n", "BUILD OK", false},
+	}
+	for _, tt := range tests {
+		if got := lastLineContains(tt.in, tt.marker); got != tt.want {
+			t.Errorf("lastLineContains(%q, %q) = %v, want %v", tt.in, tt.marker, got, tt.want)
+		}
+	}
+}
+
+func TestFirstMatch(t *testing.T) {
+	re := regexp.MustCompile(`(?i)error`)
+	tests := []struct {
+		in     string
+		want   string
+		wantOK bool
+	}{
+		{"error: first\nok\n", "error: first", true},
+		{"ok\nbuild ERROR in main.go\nerror again\n", "build ERROR in main.go", true},
+		{"ok\nok\nlast error", "last error", true},
+		{"all fine\nnothing to see\n", "", false},
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := firstMatch(tt.in, re)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("firstMatch(%q) = %q, %v, want %q, %v", tt.in, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestUniqLines(t *testing.T) {
+	tests := []struct {
+		in         string
+		ignoreCase bool
+		want       string
+	}{
+		{"a\na\nb\nb\na\n", false, "a\nb\na\n"},
+		{"Error\nerror\nERROR\nok\n", false, "Error\nerror\nERROR\nok\n"},
+		{"Error\nerror\nERROR\nok\n", true, "Error\nok\n"},
+		{"Straße\nSTRASSE\n", true, "Straße\nSTRASSE\n"}, // simple folding only
+		{"x\nx", false, "x"},
+		{"", false, ""},
+	}
+	for _, tt := range tests {
+		if got := uniqLines(tt.in, tt.ignoreCase); got != tt.want {
+			t.Errorf("uniqLines(%q, %v) = %q, want %q", tt.in, tt.ignoreCase, got, tt.want)
+		}
+	}
+}
+
+func TestTruncateBytes(t *testing.T) {
+	tests := []struct {
+		in   string
+		n    int
+		want string
+	}{
+		{"hello", 10, "hello"},
+		{"hello", 5, "hello"},
+		{"hello", 3, "hel"},
+		{"héllo", 2, "h"},  // é is 2 bytes: not split
+		{"héllo", 3, "hé"}, // exactly after é
+		{"a🙂b", 4, "a"},    // 🙂 is 4 bytes
+		{"a🙂b", 5, "a🙂"},
+		{"日本語", 7, "日本"}, // 3-byte runes
+		{"日本語", 2, ""},
+		{"x", 0, ""},
+	}
+	for _, tt := range tests {
+		got := truncateBytes(tt.in, tt.n)
+		if got != tt.want {
+			t.Errorf("truncateBytes(%q, %d) = %q, want %q", tt.in, tt.n, got, tt.want)
+		}
+		if !utf8.ValidString(got) || len(got) > tt.n && len(tt.in) > tt.n {
+			t.Errorf("truncateBytes(%q, %d) = %q: invalid or too long", tt.in, tt.n, got)
+		}
+	}
+
+	// --max-line-bytes applies it to each line.
+	got := mapLines("short\nvery long line\nwörd\n", func(line string) string { return truncateBytes(line, 6) })
+	if want := "short\nvery l\nwörd\n"; got != want {
+		t.Errorf("per line = %q, want %q", got, want)
+	}
+}
+
+func TestNumberLines(t *testing.T) {
+	tests := []struct {
+		name  string
+		in    string
+		start int
+		want  string
+	}{
+		{"from one", "a\nb\n", 1, "1  a\n2  b\n"},
+		{"from a base", "x\ny\nz", 42, "42  x\n43  y\n44  z"},
+		{"widens past a power of ten", "a\nb\nc\n", 98, " 98  a\n 99  b\n100  c\n"},
+		{"zero", "a\nb\n", 0, "0  a\n1  b\n"},
+		{"single line", "only\n", 7, "7  only\n"},
+	}
+	for _, tt := range tests {
+		if got := numberLines(tt.in, tt.start); got != tt.want {
+			t.Errorf("%s: numberLines(%q, %d) = %q, want %q", tt.name, tt.in, tt.start, got, tt.want)
+		}
+	}
+}
+
+func TestExpandTabs(t *testing.T) {
+	tests := []struct {
+		in    string
+		width int
+		want  string
+	}{
+		{"a\tb", 4, "a   b"},
+		{"\tx", 4, "    x"},
+		{"ab\tc\td", 4, "ab  c   d"},
+		{"abcd\te", 4, "abcd    e"},
+		{"界\tx", 4, "界  x"},
+		{"a\tb\n\tc\n", 8, "a       b\n        c\n"},
+		{"no tabs", 4, "no tabs"},
+		{"a\t", 2, "a "},
+	}
+	for _, tt := range tests {
+		if got := expandTabs(tt.in, tt.width); got != tt.want {
+			t.Errorf("expandTabs(%q, %d) = %q, want %q", tt.in, tt.width, got, tt.want)
+		}
+	}
+}
+
+func TestSqueezeSpaces(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"a  b   c", "a b c"},
+		{"a\t\tb", "a b"},
+		{"a \t b\t c", "a b c"},
+		{"    indented   line", "    indented line"},
+		{"\t\tkey  =\tvalue", "\t\tkey = value"},
+		{"trailing   ", "trailing "},
+		{"single spaced", "single spaced"},
+		{"   ", "   "},
+		{"", ""},
+		{"a\u00a0\u00a0b", "a\u00a0\u00a0b"}, // only spaces and tabs
+	}
+	for _, tt := range tests {
+		if got := squeezeSpaces(tt.in); got != tt.want {
+			t.Errorf("squeezeSpaces(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestSqueezeSpacesLines(t *testing.T) {
+	in := "a  b\n\n  c \t d\r\ne\t\tf"
+	want := "a b\n\n  c d\r\ne f"
+	if got := mapLines(in, squeezeSpaces); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
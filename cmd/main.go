@@ -3,52 +3,203 @@ package main
 import (
 	"bytes"
 	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
+	"math/rand/v2"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"regexp"
+	"runtime"
+	"slices"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"filippo.io/age"
 )
 
 const maxBufferSize = 10 * 1024 * 1024 // 10 MB
 
+// exitDeadline is the exit status when --deadline expires, as for timeout(1).
+const exitDeadline = 124
+
+// exitTruncated is the exit status when --error-on-truncate finds more
+// input than maxBufferSize.
+const exitTruncated = 3
+
+// Components of the escape sequences we strip, each following the ESC byte.
+const (
+	csiPattern     = `\[[0-?]*[ -/]*[@-~]`           // CSI: cursor movement, SGR, erase, ...
+	oscPattern     = `\][^\x07\x1b]*(?:\x07|\x1b\\)` // OSC: titles, hyperlinks, clipboard
+	stringPattern  = `[PX^_][^\x1b]*\x1b\\`          // DCS, SOS, PM and APC strings
+	charsetPattern = `[()][AB012]`                   // character set designation
+	simplePattern  = `[A-Z\\]`                       // two-byte escapes
+)
+
 // ansiRE matches common ANSI/OSC/DCS escape sequences so we can strip them.
 // Compiled once for performance.
 var ansiRE = regexp.MustCompile(
-	`\x1b(?:\[[0-9;?]*[ -/]*[@-~]|\][^\x07\x1b]*(?:\x07|\x1b\\)|[PX^_][^\x1b]*\x1b\\|[()][AB012]|[A-Z\\])`,
+	`\x1b(?:` + strings.Join([]string{csiPattern, oscPattern, stringPattern, charsetPattern, simplePattern}, "|") + `)`,
 )
 
+// sgrRE matches a complete SGR (colour/style) sequence.
+var sgrRE = regexp.MustCompile(`^\x1b\[[0-9;:]*m$`)
+
 // stripANSI removes terminal control sequences from s.
 func stripANSI(s string) string {
 	return ansiRE.ReplaceAllString(s, "")
 }
 
-// detectClipboardCmd returns a clipboard helper command if available.
-// It looks for wl-copy (Wayland), then xclip/xsel (X11). The bool indicates
-// whether an external helper was found.
-func detectClipboardCmd() (string, []string, bool) {
-	// Prefer wl-copy on Wayland
-	if os.Getenv("WAYLAND_DISPLAY") != "" {
-		if p, err := exec.LookPath("wl-copy"); err == nil {
-			return p, nil, true
+// stripANSIKeepSGR removes terminal control sequences from s except SGR
+// colour and style codes.
+func stripANSIKeepSGR(s string) string {
+	return ansiRE.ReplaceAllStringFunc(s, func(seq string) string {
+		if sgrRE.MatchString(seq) {
+			return seq
 		}
+		return ""
+	})
+}
+
+// clipboardHelperArgs are the known clipboard helpers and the arguments
+// each needs to write the clipboard.
+var clipboardHelperArgs = map[string][]string{
+	"pbcopy":  nil,
+	"wl-copy": nil,
+	"xclip":   {"-selection", "clipboard"},
+	"xsel":    {"--clipboard", "--input"},
+}
+
+// clipboardCandidates returns the path of every clipboard helper usable in
+// this session, most preferred first: pbcopy on macOS, wl-copy on Wayland,
+// then xclip and xsel on X11. wl-copy is tried anywhere as a last-ditch
+// helper, but only when nothing else was found.
+func clipboardCandidates() []string {
+	var found []string
+	look := func(name string) {
+		if p, err := exec.LookPath(name); err == nil {
+			found = append(found, p)
+		}
+	}
+	if runtime.GOOS == "darwin" {
+		look("pbcopy")
+	}
+	if os.Getenv("WAYLAND_DISPLAY") != "" {
+		look("wl-copy")
 	}
-	// X11 helpers
 	if os.Getenv("DISPLAY") != "" {
-		if p, err := exec.LookPath("xclip"); err == nil {
-			return p, []string{"-selection", "clipboard"}, true
+		look("xclip")
+		look("xsel")
+	}
+	if len(found) == 0 {
+		look("wl-copy")
+	}
+	return found
+}
+
+// detectClipboardCmd returns a clipboard helper command if available.
+// It looks for pbcopy (macOS), wl-copy (Wayland), then xclip/xsel (X11).
+// The bool indicates whether an external helper was found.
+func detectClipboardCmd() (string, []string, bool) {
+	candidates := clipboardCandidates()
+	if len(candidates) == 0 {
+		return "", nil, false
+	}
+	bin := candidates[0]
+	return bin, clipboardHelperArgs[filepath.Base(bin)], true
+}
+
+// destinations are the values of the --*-in options that say whether a
+// transform applies to the clipboard copy, the -f file or both.
+var destinations = []string{"clipboard", "file", "both"}
+
+// pasteboards are the macOS pasteboards pbcopy -pboard accepts.
+var pasteboards = []string{"general", "find", "font", "ruler"}
+
+// errAmbiguousHelper means --strict-detect found more than one helper.
+var errAmbiguousHelper = errors.New("several clipboard helpers found")
+
+// chooseClipboardCmd is detectClipboardCmd honouring opts.helper and
+// opts.strict. A helper named with --clipboard-cmd must be installed, and
+// under --strict-detect finding more than one helper is an error. pbcopy is
+// pointed at opts.pasteboard.
+func chooseClipboardCmd(opts clipOptions) (string, []string, bool, error) {
+	bin, args, ok, err := pickClipboardCmd(opts)
+	if ok && filepath.Base(bin) == "pbcopy" && opts.pasteboard != "" && opts.pasteboard != "general" {
+		args = append(slices.Clone(args), "-pboard", opts.pasteboard)
+	}
+	return bin, args, ok, err
+}
+
+// pickClipboardCmd does the choosing for chooseClipboardCmd.
+func pickClipboardCmd(opts clipOptions) (string, []string, bool, error) {
+	if opts.helper != "" {
+		bin, err := exec.LookPath(opts.helper)
+		if err != nil {
+			return "", nil, false, fmt.Errorf("--clipboard-cmd %s: %w", opts.helper, err)
 		}
-		if p, err := exec.LookPath("xsel"); err == nil {
-			return p, []string{"--clipboard", "--input"}, true
+		return bin, clipboardHelperArgs[opts.helper], true, nil
+	}
+	if opts.strict {
+		if candidates := clipboardCandidates(); len(candidates) > 1 {
+			names := make([]string, len(candidates))
+			for i, c := range candidates {
+				names[i] = filepath.Base(c)
+			}
+			return "", nil, false, fmt.Errorf("%w (%s); pick one with --clipboard-cmd", errAmbiguousHelper, strings.Join(names, ", "))
 		}
 	}
-	// Try wl-copy anywhere as a last-ditch helper
-	if p, err := exec.LookPath("wl-copy"); err == nil {
-		return p, nil, true
+	bin, args, ok := detectClipboardCmd()
+	return bin, args, ok, nil
+}
+
+// describeClipboardMethod reports the method writeToClipboardMethod would
+// try first, with the full helper command line, without copying anything.
+func describeClipboardMethod(opts clipOptions) (string, error) {
+	if opts.helper == methodOSC52 {
+		return methodOSC52, nil
+	}
+	bin, args, ok, err := chooseClipboardCmd(opts)
+	if err != nil {
+		return "", err
+	}
+	if !ok && isWSL() {
+		bin, ok = findClipExe()
 	}
-	return "", nil, false
+	if !ok {
+		return methodOSC52, nil
+	}
+	if filepath.Base(bin) == "wl-copy" {
+		args = append([]string{"--paste-once"}, args...)
+	}
+	return filepath.Base(bin) + ": " + strings.Join(append([]string{bin}, args...), " "), nil
+}
+
+// helperEnvVars are the variables a clipboard helper needs to reach the
+// display server: the display names, X authority, the Wayland socket
+// directory and, under WSL, the interop socket for clip.exe.
+var helperEnvVars = []string{"PATH", "DISPLAY", "WAYLAND_DISPLAY", "XAUTHORITY", "XDG_RUNTIME_DIR", "WSL_INTEROP"}
+
+// helperEnv returns the environment for clipboard helpers: the full
+// environment, or with clean only helperEnvVars.
+func helperEnv(clean bool) []string {
+	if !clean {
+		return os.Environ()
+	}
+	var env []string
+	for _, name := range helperEnvVars {
+		if v, ok := os.LookupEnv(name); ok {
+			env = append(env, name+"="+v)
+		}
+	}
+	return env
 }
 
 // writeUsingCmd pipes content to an external clipboard helper.
@@ -56,7 +207,7 @@ func detectClipboardCmd() (string, []string, bool) {
 // --paste-once makes it exit immediately after the first paste request is
 // served (or right after the data is offered), which prevents goclip from
 // hanging indefinitely.
-func writeUsingCmd(bin string, args []string, content string) error {
+func writeUsingCmd(bin string, args []string, env []string, content string) error {
 	// wl-copy without any flag forks into the background and never exits,
 	// so cmd.Wait() would block forever. --paste-once (-o) tells wl-copy to
 	// exit as soon as the clipboard content has been served once, which is
@@ -66,7 +217,7 @@ func writeUsingCmd(bin string, args []string, content string) error {
 	}
 
 	cmd := exec.Command(bin, args...)
-	cmd.Env = os.Environ()
+	cmd.Env = env
 
 	stdin, err := cmd.StdinPipe()
 	if err != nil {
@@ -96,81 +247,487 @@ func writeUsingCmd(bin string, args []string, content string) error {
 	return nil
 }
 
+// osc52Sequence builds the OSC 52 sequence that sets the clipboard ('c') to
+// content. It is BEL-terminated, which most terminals expect; with both, an
+// ST-terminated copy follows for terminals that drop the BEL form. Both set
+// the same content, so a terminal honouring both just sets it twice.
+func osc52Sequence(content string, both bool) string {
+	enc := base64.StdEncoding.EncodeToString([]byte(content))
+	seq := "\x1b]52;c;" + enc + "\x07"
+	if both {
+		seq += "\x1b]52;c;" + enc + "\x1b\\"
+	}
+	return seq
+}
+
+// errNoTTY means OSC 52 could not be used because no terminal is attached
+// (cron jobs, some containers).
+var errNoTTY = errors.New("OSC 52 requires a terminal; none attached")
+
 // writeClipboardOSC52 attempts to copy via OSC 52 sequence written to /dev/tty.
 // Many modern terminal emulators support it. This avoids external binaries.
-func writeClipboardOSC52(content string) error {
-	tty, err := os.OpenFile("/dev/tty", os.O_WRONLY, 0)
+// If /dev/tty can't be opened but stderr is a terminal, stderr is used. In
+// an SSH session the sequence is also written to $SSH_TTY, which may be the
+// only terminal that reaches the client; it succeeds if any write does.
+// With opts.tty the sequence goes there and nowhere else.
+func writeClipboardOSC52(content string, opts clipOptions) error {
+	seq := osc52Sequence(content, opts.osc52Both)
+	if opts.tty != "" {
+		if err := writeTTYFile(opts.tty, seq); err != nil {
+			return fmt.Errorf("write OSC52: %w", err)
+		}
+		return nil
+	}
+	var tty io.Writer
+	var ttyFile *os.File
+	f, err := os.OpenFile("/dev/tty", os.O_WRONLY, 0)
+	if err == nil {
+		defer f.Close()
+		tty, ttyFile = f, f
+	} else if isTerminal(os.Stderr.Fd()) {
+		tty, ttyFile = os.Stderr, os.Stderr
+	}
+
+	var wrote bool
+	var writeErr error
+	if tty != nil {
+		if _, err := io.WriteString(tty, seq); err != nil {
+			writeErr = err
+		} else {
+			wrote = true
+		}
+	}
+	if path := os.Getenv("SSH_TTY"); path != "" && !sameTTY(path, ttyFile) {
+		if err := writeTTYFile(path, seq); err != nil {
+			if writeErr == nil {
+				writeErr = err
+			}
+		} else {
+			wrote = true
+		}
+	}
+
+	switch {
+	case wrote:
+		return nil
+	case writeErr != nil:
+		return fmt.Errorf("write OSC52: %w", writeErr)
+	default:
+		return fmt.Errorf("%w (%v)", errNoTTY, err)
+	}
+}
+
+// sameTTY reports whether path names the terminal already written to, f.
+// The /dev/tty alias can't be compared by inode, so for it the standard
+// streams that are terminals stand in: the controlling terminal is almost
+// always the one they are attached to.
+func sameTTY(path string, f *os.File) bool {
+	if f == nil {
+		return false
+	}
+	a, err := os.Stat(path)
 	if err != nil {
-		return fmt.Errorf("open /dev/tty: %w", err)
+		return false
+	}
+	same := func(f *os.File) bool {
+		b, err := f.Stat()
+		return err == nil && os.SameFile(a, b)
+	}
+	if f.Name() != "/dev/tty" {
+		return same(f)
+	}
+	for _, std := range []*os.File{os.Stdin, os.Stdout, os.Stderr} {
+		if isTerminal(std.Fd()) && same(std) {
+			return true
+		}
 	}
-	defer tty.Close()
+	return false
+}
 
-	enc := base64.StdEncoding.EncodeToString([]byte(content))
-	seq := fmt.Sprintf("\x1b]52;c;%s\x07", enc) // clipboard ('c')
-	_, err = io.WriteString(tty, seq)
+// writeTTYFile writes seq to the terminal at path, such as the SSH
+// session's. A regular file is appended to rather than overwritten.
+func writeTTYFile(path, seq string) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
 	if err != nil {
-		return fmt.Errorf("write OSC52: %w", err)
+		return err
 	}
-	return nil
+	defer f.Close()
+	_, err = io.WriteString(f, seq)
+	return err
+}
+
+// clipOptions tunes how writeToClipboard reaches the clipboard.
+type clipOptions struct {
+	// noFallback returns a helper's error instead of falling back to OSC 52.
+	noFallback bool
+	// osc52Both sends the OSC 52 sequence both BEL- and ST-terminated.
+	osc52Both bool
+	// cleanEnv runs helpers with only helperEnvVars from the environment.
+	cleanEnv bool
+	// helper names the helper to use (a clipboardHelperArgs key or
+	// methodOSC52) instead of detecting one.
+	helper string
+	// strict refuses to guess when several helpers are available.
+	strict bool
+	// tty, if set, is where OSC 52 is written instead of the terminal.
+	tty string
+	// pasteboard is the macOS pasteboard pbcopy writes to; empty or
+	// "general" leaves pbcopy at its default.
+	pasteboard string
 }
 
 // writeToClipboard tries external helpers first, then falls back to OSC 52.
-func writeToClipboard(content string) error {
-	if bin, args, ok := detectClipboardCmd(); ok {
-		if err := writeUsingCmd(bin, args, content); err == nil {
-			return nil
+// Under WSL, clip.exe is tried after wl-copy since WSLg often lacks
+// wl-clipboard.
+func writeToClipboard(content string, opts clipOptions) error {
+	_, err := writeToClipboardMethod(content, opts)
+	return err
+}
+
+// methodOSC52 names the OSC 52 escape sequence as a copy method.
+const methodOSC52 = "osc52"
+
+// writeToClipboardMethod is writeToClipboard, also returning how the content
+// was copied: the helper's name (such as "wl-copy" or "clip.exe") or
+// methodOSC52.
+func writeToClipboardMethod(content string, opts clipOptions) (string, error) {
+	if opts.helper == methodOSC52 {
+		if err := writeClipboardOSC52(content, opts); err != nil {
+			return "", err
+		}
+		return methodOSC52, nil
+	}
+	bin, args, ok, err := chooseClipboardCmd(opts)
+	if err != nil {
+		return "", err
+	}
+	if ok {
+		err := writeUsingCmd(bin, args, helperEnv(opts.cleanEnv), content)
+		if err == nil {
+			return filepath.Base(bin), nil
+		}
+		if opts.noFallback {
+			return "", err
 		} // if it fails, try OSC52 as fallback
 	}
-	if err := writeClipboardOSC52(content); err != nil {
-		return fmt.Errorf("no external clipboard helper and OSC52 failed: %w", err)
+	if isWSL() {
+		if bin, ok := findClipExe(); ok {
+			err := writeUsingCmd(bin, nil, helperEnv(opts.cleanEnv), encodeUTF16LE(content))
+			if err == nil {
+				return filepath.Base(bin), nil
+			}
+			if opts.noFallback {
+				return "", err
+			}
+		}
+	}
+	if err := writeClipboardOSC52(content, opts); err != nil {
+		if errors.Is(err, errNoTTY) {
+			return "", fmt.Errorf("no external clipboard helper, and %w", err)
+		}
+		return "", fmt.Errorf("no external clipboard helper and OSC52 failed: %w", err)
 	}
-	return nil
+	return methodOSC52, nil
 }
 
-func writeToFile(path, content string, appendMode bool) error {
+// expandFileTemplate fills in a --file-header/--file-footer template.
+// {time} is replaced with t in RFC 3339 format and {bytes} with n.
+func expandFileTemplate(tpl string, t time.Time, n int) string {
+	return strings.NewReplacer(
+		"{time}", t.Format(time.RFC3339),
+		"{bytes}", strconv.Itoa(n),
+	).Replace(tpl)
+}
+
+// fileEntry brackets content with the optional header and footer lines.
+func fileEntry(header, content, footer string) string {
+	var b strings.Builder
+	if header != "" {
+		b.WriteString(header + "\n")
+	}
+	b.WriteString(content)
+	if footer != "" {
+		if content != "" && !strings.HasSuffix(content, "\n") {
+			b.WriteString("\n")
+		}
+		b.WriteString(footer + "\n")
+	}
+	return b.String()
+}
+
+// fileOptions controls how writeToFile writes the -f file.
+type fileOptions struct {
+	appendMode bool
+	// atomic replaces the file via a temporary file and rename, so it is
+	// never left half-written. It does not apply when appending.
+	atomic bool
+	// mode is the permission for a newly created file (subject to the
+	// umask unless written atomically).
+	mode os.FileMode
+	// enforceMode also sets mode on an existing file, exactly.
+	enforceMode bool
+	// mkdir creates missing parent directories with dirMode.
+	mkdir   bool
+	dirMode os.FileMode
+}
+
+// logRecord is one -f entry with --log-format jsonl.
+type logRecord struct {
+	TS      string `json:"ts"`
+	Label   string `json:"label,omitempty"`
+	Bytes   int    `json:"bytes"`
+	Content string `json:"content"`
+}
+
+// jsonlEntry formats content as a single JSON line for the -f file.
+func jsonlEntry(t time.Time, label, content string) (string, error) {
+	var b strings.Builder
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	err := enc.Encode(logRecord{TS: t.Format(time.RFC3339), Label: label, Bytes: len(content), Content: content})
+	return b.String(), err
+}
+
+// parseFileMode parses an octal permission string such as "0600".
+func parseFileMode(s string) (os.FileMode, error) {
+	m, err := strconv.ParseUint(s, 8, 32)
+	if err != nil || m > 0o777 {
+		return 0, fmt.Errorf("%q is not an octal permission like 0600", s)
+	}
+	return os.FileMode(m), nil
+}
+
+// writeToFile writes data to path, truncating it unless opts.appendMode is
+// set.
+func writeToFile(path, data string, opts fileOptions) error {
+	if opts.mkdir {
+		if err := os.MkdirAll(filepath.Dir(path), opts.dirMode); err != nil {
+			return fmt.Errorf("create directory: %w", err)
+		}
+	}
+	if opts.atomic && !opts.appendMode {
+		return writeFileAtomic(path, data, opts)
+	}
 	flags := os.O_CREATE | os.O_WRONLY
-	if appendMode {
+	if opts.appendMode {
 		flags |= os.O_APPEND
 	} else {
 		flags |= os.O_TRUNC
 	}
-	f, err := os.OpenFile(path, flags, 0o644)
+	f, err := os.OpenFile(path, flags, opts.mode)
 	if err != nil {
 		return fmt.Errorf("open file: %w", err)
 	}
 	defer f.Close()
+	if opts.enforceMode {
+		if err := f.Chmod(opts.mode); err != nil {
+			return fmt.Errorf("set file mode: %w", err)
+		}
+	}
 
-	if _, err := io.WriteString(f, content); err != nil {
+	if _, err := io.WriteString(f, data); err != nil {
 		return fmt.Errorf("write file: %w", err)
 	}
 	return nil
 }
 
+// writeFileAtomic writes data to a temporary file next to path and renames
+// it over path once it is complete, keeping the permissions of any file it
+// replaces unless opts.enforceMode is set. On failure the original file is
+// left as it was.
+func writeFileAtomic(path, data string, opts fileOptions) (err error) {
+	mode := opts.mode
+	if fi, err := os.Stat(path); err == nil && !opts.enforceMode {
+		mode = fi.Mode().Perm()
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("create temp file: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+	if _, err := io.WriteString(tmp, data); err != nil {
+		return fmt.Errorf("write file: %w", err)
+	}
+	if err := tmp.Chmod(mode); err != nil {
+		return fmt.Errorf("write file: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		return fmt.Errorf("write file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("write file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("replace file: %w", err)
+	}
+	return nil
+}
+
 func usageText(prog string) string {
 	return fmt.Sprintf(`%s — copy piped output to the system clipboard and optionally log it.
 
 Usage:
   some_command | %s [options]
+  %s [options] file...
+  %s [options] <command>
 
 Examples:
   ls -la | %s                     # copy stdout to clipboard
   mytool 2>&1 | %s                 # copy both stdout and stderr
   some_cmd | %s -f output.log      # save a copy to a file and copy to clipboard
   some_cmd | %s -q --no-clip       # don't print to stdout, only save to file (if -f) or nothing
+  %s --with-header a.go b.go       # copy several files, each under a "==> name <==" header
+
+Commands:
+  sync-primary   copy the primary selection into the clipboard
+  paste          print the clipboard (--primary, --list-targets, --prefer, --decompress)
+  snapshot       save the clipboard to a timestamped file (--dir, --primary)
+  monitor        log each new clipboard value (--log, --interval, --primary)
 
 Options:
-`, prog, prog, prog, prog, prog, prog)
+`, prog, prog, prog, prog, prog, prog, prog, prog, prog)
 }
 
 func main() {
 	// Flags
+	human := flag.Bool("human", false, "show sizes in status messages as KB/MB")
 	quiet := flag.Bool("q", false, "quiet — don't print piped input to stdout")
 	strip := flag.Bool("s", true, "strip ANSI control sequences before copying")
-	trim := flag.Bool("t", false, "trim leading/trailing whitespace before copying")
+	keepSGR := flag.Bool("keep-sgr", false, "when stripping, keep SGR colour codes and drop only other sequences")
+	trim := flag.Bool("t", false, "trim leading/trailing whitespace before copying (same as --trim both)")
+	trimMode := flag.String("trim", "none", "trim whitespace `mode`: left, right, both (ends) or none")
 	notify := flag.Bool("n", false, "send a desktop notification after copying")
 	logFile := flag.String("f", "", "save output to file (overwrites unless -a)")
 	appendFile := flag.Bool("a", false, "append to file when used with -f")
+	fileHeader := flag.String("file-header", "", "line written before the content in the -f file ({time} = start, {bytes})")
+	fileFooter := flag.String("file-footer", "", "line written after the content in the -f file ({time} = end, {bytes})")
 	noClip := flag.Bool("no-clip", false, "do not copy to clipboard (useful with -f)")
+	noFallback := flag.Bool("no-fallback", false, "fail if the clipboard helper fails instead of falling back to OSC 52")
+	cleanEnv := flag.Bool("clean-env", false, "run clipboard helpers with a minimal environment (display variables and PATH)")
+	printMethod := flag.Bool("print-method", false, "print the clipboard method and command that would be used, then exit")
+	clipboardCmd := flag.String("clipboard-cmd", "", "use this clipboard `helper` (pbcopy, wl-copy, xclip, xsel or osc52) instead of detecting one")
+	pasteboard := flag.String("pasteboard", "general", "macOS pasteboard for pbcopy: general, find, font or ruler")
+	strictDetect := flag.Bool("strict-detect", false, "fail instead of picking one when several clipboard helpers are available")
+	ttyPath := flag.String("tty", "", "write OSC 52 to this terminal (or file/pipe) instead of /dev/tty")
+	osc52Both := flag.Bool("osc52-both", false, "send OSC 52 both BEL- and ST-terminated for picky terminals")
+	maxLineBytes := flag.Int("max-line-bytes", 0, "truncate lines longer than N bytes, at a character boundary")
+	maxLineLen := flag.Int("max-line-length", 0, "drop lines wider than N display columns")
+	maxLineMode := flag.String("max-line-mode", "drop", "what --max-line-length does with long lines: drop or truncate")
+	echoTimestamp := flag.Bool("echo-timestamp", false, "prefix each line echoed to stdout with a timestamp (content is unchanged)")
+	grep := flag.String("grep", "", "keep only lines matching the regular expression")
+	grepV := flag.String("grep-v", "", "drop lines matching the regular expression")
+	highlight := flag.Bool("highlight", false, "highlight --grep matches in the stdout echo (the copy stays plain)")
+	first := flag.String("first", "", "copy only the first line matching the regular expression; exit 1 if none")
+	countMatches := flag.Bool("count-matches", false, "report how many lines --grep matched or --grep-v dropped")
+	requireMarker := flag.String("require-marker", "", "copy only if the last input line contains this marker; exit 1 otherwise")
+	since := flag.Duration("since", 0, "keep only lines whose leading timestamp is within this duration of now")
+	sinceDropUntimed := flag.Bool("since-drop-untimed", false, "with --since, also drop lines without a timestamp")
+	absPaths := flag.Bool("abs-paths", false, "treat each line as a path and make it absolute")
+	dedentFlag := flag.Bool("dedent", false, "remove indentation common to all non-blank lines")
+	squeezeWS := flag.Bool("squeeze-ws", false, "collapse runs of spaces and tabs within each line to a single space")
+	metricsFile := flag.String("metrics-file", "", "with --watch-file, keep copy counters as JSON in `path`, updated after every copy")
+	watch := flag.String("watch-file", "", "copy `path` and re-copy it whenever it changes (applies -s and -t/--trim only)")
+	notifyInterval := flag.Duration("notify-interval", 10*time.Second, "with --watch-file and -n, send at most one notification per interval")
+	jsonField := flag.String("json-field", "", "parse the input as JSON and copy the value at `path` (e.g. .items[0].name)")
+	decodeQP := flag.Bool("decode-qp", false, "decode quoted-printable input (=20, soft line breaks) before copying")
+	stripHTMLFlag := flag.Bool("strip-html", false, "convert HTML to plain text (drop tags, scripts and styles; decode entities)")
+	sortFlag := flag.Bool("sort", false, "sort the lines before copying")
+	sortReverse := flag.Bool("sort-reverse", false, "sort in reverse order (implies --sort)")
+	sortNumeric := flag.Bool("sort-numeric", false, "sort by each line's leading number (implies --sort)")
+	sortUnique := flag.Bool("sort-unique", false, "sort and drop duplicate lines, like sort -u (implies --sort)")
+	uniq := flag.Bool("uniq", false, "collapse consecutive duplicate lines, like uniq")
+	ignoreCase := flag.Bool("ignore-case", false, "make --grep, --grep-v, --uniq and --sort case-insensitive")
+	shuffle := flag.Bool("shuffle", false, "put the lines in random order")
+	seed := flag.Uint64("seed", 0, "seed for --shuffle, for a repeatable order (default: random)")
+	align := flag.Bool("align", false, "re-align whitespace-separated columns into a table")
+	mdTable := flag.Bool("md-table", false, "convert whitespace- or tab-separated input to a Markdown table (first line is the header)")
+	tsvToCSV := flag.Bool("tsv-to-csv", false, "convert tab-separated input to CSV")
+	csvToTSV := flag.Bool("csv-to-tsv", false, "convert CSV input to tab-separated values")
+	selectFlag := flag.Bool("select-lines", false, "interactively choose which lines to copy (needs a terminal)")
+	envExpand := flag.Bool("env-expand", false, "expand $VAR references from the environment")
+	envKeepMissing := flag.Bool("env-expand-keep-missing", false, "with --env-expand, leave undefined variables as-is")
+	stripComments := flag.String("strip-comments", "", "drop lines starting with any of the comma-separated `PREFIXES` (after indentation), e.g. '//,;'; an empty list means '#'")
+	join := flag.String("join", "", "join lines with `SEP` (\\t and other escapes allowed); an empty SEP joins with a space")
+	joinSkipBlank := flag.Bool("join-skip-blank", false, "with --join, drop blank lines instead of joining them")
+	upper := flag.Bool("upper", false, "convert the content to upper case")
+	lower := flag.Bool("lower", false, "convert the content to lower case")
+	truncMiddle := flag.Int("truncate-middle", 0, "shorten content wider than N display columns by cutting out the middle")
+	shellQuoteFlag := flag.Bool("shell-quote", false, "quote the output as one POSIX shell word")
+	shellQuoteAll := flag.Bool("shell-quote-all-lines", false, "quote each line as a shell word, joined by spaces")
+	number := flag.Bool("number", false, "prefix each line with its line number")
+	numberStart := flag.Int("number-start", 1, "first line number for --number, e.g. where a snippet starts in its file (implies --number)")
+	onSuccess := flag.String("on-success", "", "run a shell `command` after a successful copy ($GOCLIP_BYTES and $GOCLIP_METHOD are set)")
+	onSuccessFatal := flag.Bool("on-success-fatal", false, "exit 1 if the --on-success command fails (default: warn only)")
+	onFailure := flag.String("on-failure", "", "run a shell `command` if copying to the clipboard fails ($GOCLIP_ERROR is set)")
+	pipeCmd := flag.String("pipe", "", "filter the content through a shell `command` before copying")
+	pipePreserveExit := flag.Bool("pipe-preserve-exit", false, "if the --pipe command fails, exit with its status (128+N if killed by signal N)")
+	codeFlag := flag.Bool("code", false, "wrap the output in a fenced Markdown code block")
+	lang := flag.String("lang", "", "language tag for --code (default: guessed)")
+	codeFilename := flag.String("filename", "", "file name used to guess the --code language (default: the single file argument)")
+	urlOnly := flag.Bool("url", false, "copy only the first http(s) URL in the output")
+	urlAll := flag.Bool("url-all", false, "copy every http(s) URL in the output, one per line")
+	urlMissing := flag.String("url-missing", "error", "with --url/--url-all and no URL found: `error` (exit 1) or keep (copy the output as is)")
+	pasteInto := flag.Bool("paste-into", false, "after copying, press Ctrl+V in the focused window (needs xdotool or wtype)")
+	openFlag := flag.Bool("open", false, "open the content in the default browser if it is a single URL")
+	qr := flag.Bool("qr", false, "also print the content as a QR code to stderr (max 271 bytes)")
+	edit := flag.Bool("edit", false, "open the content in $EDITOR before copying")
+	preview := flag.Int("preview", 0, "print the first N lines of the content to stderr before copying (even with -q)")
+	copyCount := flag.Bool("copy-count", false, "copy the count from --count-metric instead of the content")
+	countMetric := flag.String("count-metric", "lines", "what --copy-count counts: lines, words, bytes or chars")
+	countOf := flag.String("count-of", "", "report how many times `STR` occurs in the output (non-overlapping)")
+	countOfRegex := flag.String("count-of-regex", "", "report how many matches of `RE` the output contains")
+	measure := flag.Bool("measure", false, "report the content size in bytes, runes and display columns")
+	label := flag.String("label", "", "name this run in the notification title and --json output")
+	jsonOut := flag.Bool("json", false, "print a JSON summary to stdout (implies -q)")
+	rotate := flag.String("rotate", "", "split content on this delimiter (\\n escapes allowed) and copy each segment in turn")
+	rotateDelay := flag.Duration("rotate-delay", 3*time.Second, "pause between segments with --rotate")
+	compress := flag.Bool("compress", false, "copy gzip+base64 content behind a marker (non-standard; expand with 'goclip paste --decompress')")
+	skipIdentical := flag.Bool("skip-identical", false, "don't write the clipboard if it already holds exactly this content")
+	appendClip := flag.Bool("append-clipboard", false, "append to the current clipboard contents instead of replacing them")
+	appendSep := flag.String("append-separator", "\\n", "separator used by --append-clipboard (\\n escapes allowed)")
+	pastebin := flag.Bool("pastebin", false, "if no clipboard works, upload to a paste service and print the URL ($GOCLIP_PASTEBIN_URL)")
+	cliphist := flag.Bool("cliphist", false, "also store the copy in cliphist history (best-effort)")
+	history := flag.Bool("history", false, "record each copy in the history file ($XDG_STATE_HOME/goclip)")
+	historyDedup := flag.Int("history-dedup-window", 1, "with --history, skip a copy identical to any of the last N entries (0 = record every copy)")
+	fileMode := flag.String("file-mode", "0644", "permissions (octal) for a -f file this creates")
+	enforceMode := flag.Bool("enforce-mode", false, "also apply --file-mode to an existing -f file")
+	toEncoding := flag.String("to-encoding", "utf-8", "character encoding of the -f file: utf-8, utf-16le, utf-16be or latin1 (the clipboard stays UTF-8)")
+	logFormat := flag.String("log-format", "raw", "format of -f entries: raw, or jsonl for one JSON object per entry")
+	mkdir := flag.Bool("mkdir", false, "create missing parent directories of the -f file")
+	dirMode := flag.String("dir-mode", "0755", "permissions (octal) for directories created by --mkdir")
+	atomic := flag.Bool("atomic", false, "replace the -f file atomically (temp file + rename); not used with -a")
+	encrypt := flag.Bool("encrypt", false, "encrypt the -f output in age format (passphrase from $GOCLIP_PASSPHRASE); read it back with 'age -d'")
+	recipient := flag.String("recipient", "", "with --encrypt, encrypt to this age public `key` (age1...) instead of a passphrase")
+	secret := flag.Bool("secret", false, "handle the input as a secret: no echo, unencrypted file, history, preview or JSON content; clear after 45s")
+	warnSecrets := flag.Bool("warn-secrets", false, "warn on stderr if the content looks like it holds credentials (it is still copied unchanged)")
+	clearAfter := flag.Duration("clear-after", 0, "clear the clipboard after this long if it still holds the copy (0 = never)")
+	diffLast := flag.Bool("diff-last", false, "print a diff against the last history entry instead of copying")
+	withHeader := flag.Bool("with-header", false, "precede each input with a \"==> name <==\" header line")
+	flushEcho := flag.Bool("flush", false, "sync the stdout echo after every line (for a file being followed)")
+	fd := flag.Int("fd", 0, "read standard input from file descriptor `N` instead of 0")
+	stdinLabel := flag.String("stdin-label", "standard input", "name used for stdin in --with-header")
+	binaryOK := flag.Bool("binary", false, "copy input even if it looks binary")
+	lineRange := flag.String("lines", "", "copy only lines `START:END` (1-based, inclusive; either may be omitted)")
+	deadline := flag.Duration("deadline", 0, "give up with exit status 124 if not done within this long (0 = no limit)")
+	wait := flag.Duration("wait", 0, "if the input is empty, keep retrying for up to this long (for slow producers)")
+	errorOnTruncate := flag.Bool("error-on-truncate", false, "exit with status 3 instead of copying if the input exceeds the 10 MB limit")
+	sample := flag.Int64("sample", 0, "copy only the first N bytes of the input (at a character boundary), reading no further")
+	byteRange := flag.String("bytes", "", "copy only the raw input bytes in `START:END` (end exclusive; either may be omitted)")
+	expandTabsN := flag.Int("expand-tabs", 0, "replace tabs with spaces to the next multiple of N columns (see --expand-tabs-in)")
+	expandTabsIn := flag.String("expand-tabs-in", "clipboard", "where --expand-tabs applies: clipboard, file or both")
+	maxClipBytes := flag.Int("max-clipboard-bytes", 0, "cut the clipboard copy (not the -f file) to at most N bytes, at a character boundary")
+	crlfToLF := flag.Bool("crlf-to-lf", false, "convert CRLF line endings to LF (see --crlf-to-lf-in)")
+	crlfToLFIn := flag.String("crlf-to-lf-in", "clipboard", "where --crlf-to-lf applies: clipboard, file or both")
+	encodeB64 := flag.Bool("encode-base64", false, "copy the raw input base64-encoded (skips ANSI stripping)")
+	encodeB64URL := flag.Bool("encode-base64url", false, "copy the raw input as URL-safe base64 (- and _ instead of + and /)")
+	decodeB64URL := flag.Bool("decode-base64url", false, "decode URL-safe base64 input before processing it")
+	hexFlag := flag.Bool("hex", false, "copy the raw input hex-encoded (skips ANSI stripping)")
+	unhex := flag.Bool("unhex", false, "decode hex input (whitespace ignored) before processing it")
 	help := flag.Bool("h", false, "show help")
 	flag.Parse()
 
@@ -179,75 +736,929 @@ func main() {
 		flag.PrintDefaults()
 		return
 	}
+	if *jsonOut || *diffLast {
+		*quiet = true
+	}
+	if *secret {
+		*quiet = true
+		ignore := func(name string) {
+			fmt.Fprintf(os.Stderr, "Warning: --secret ignores %s.\n", name)
+		}
+		if *logFile != "" && !*encrypt {
+			ignore("-f")
+			*logFile = ""
+		}
+		for _, opt := range []struct {
+			name string
+			on   *bool
+		}{
+			{"--history", history}, {"--cliphist", cliphist}, {"--pastebin", pastebin},
+			{"--qr", qr}, {"--diff-last", diffLast},
+		} {
+			if *opt.on {
+				ignore(opt.name)
+				*opt.on = false
+			}
+		}
+		if *preview > 0 {
+			ignore("--preview")
+			*preview = 0
+		}
+		if !isFlagSet("clear-after") {
+			*clearAfter = secretClearAfter
+		}
+	}
 
-	// Ensure there is piped input on stdin
-	stat, err := os.Stdin.Stat()
+	if *maxLineMode != "drop" && *maxLineMode != "truncate" {
+		fmt.Fprintln(os.Stderr, "invalid --max-line-mode:", *maxLineMode, "(want drop or truncate)")
+		os.Exit(1)
+	}
+	fileModeBits, err := parseFileMode(*fileMode)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "invalid --file-mode:", err)
+		os.Exit(1)
+	}
+	dirModeBits, err := parseFileMode(*dirMode)
 	if err != nil {
-		fmt.Fprintln(os.Stderr, "error: unable to stat stdin:", err)
+		fmt.Fprintln(os.Stderr, "invalid --dir-mode:", err)
 		os.Exit(1)
 	}
-	if (stat.Mode() & os.ModeCharDevice) != 0 {
-		fmt.Fprintln(os.Stderr, "No piped input detected. Use: some_command |", os.Args[0])
-		fmt.Fprintln(os.Stderr, "Use -h for help and examples.")
+	var encTo age.Recipient
+	if *recipient != "" && !*encrypt {
+		fmt.Fprintln(os.Stderr, "--recipient needs --encrypt")
+		os.Exit(1)
+	}
+	if *encrypt {
+		if *logFile == "" || *appendFile {
+			fmt.Fprintln(os.Stderr, "--encrypt needs -f and can't be combined with -a")
+			os.Exit(1)
+		}
+		if encTo, err = encryptionRecipient(*recipient); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+	if _, _, err := encodeText("", *toEncoding, false); err != nil {
+		fmt.Fprintln(os.Stderr, "invalid --to-encoding:", err)
+		os.Exit(1)
+	}
+	if *logFormat != "raw" && *logFormat != "jsonl" {
+		fmt.Fprintln(os.Stderr, "invalid --log-format:", *logFormat, "(want raw or jsonl)")
+		os.Exit(1)
+	}
+	if !slices.Contains(countMetrics, *countMetric) {
+		fmt.Fprintln(os.Stderr, "invalid --count-metric:", *countMetric, "(want lines, words, bytes or chars)")
+		os.Exit(1)
+	}
+	for _, opt := range []struct{ name, value string }{
+		{"--expand-tabs-in", *expandTabsIn}, {"--crlf-to-lf-in", *crlfToLFIn},
+	} {
+		if !slices.Contains(destinations, opt.value) {
+			fmt.Fprintln(os.Stderr, "invalid "+opt.name+":", opt.value, "(want clipboard, file or both)")
+			os.Exit(1)
+		}
+	}
+	if !slices.Contains(trimModes, *trimMode) {
+		fmt.Fprintln(os.Stderr, "invalid --trim:", *trimMode, "(want left, right, both or none)")
+		os.Exit(1)
+	}
+	if *trim && !isFlagSet("trim") {
+		*trimMode = "both"
+	}
+	if *historyDedup < 0 {
+		fmt.Fprintln(os.Stderr, "invalid --history-dedup-window:", *historyDedup)
+		os.Exit(1)
+	}
+	if *urlMissing != "error" && *urlMissing != "keep" {
+		fmt.Fprintln(os.Stderr, "invalid --url-missing:", *urlMissing, "(want error or keep)")
 		os.Exit(1)
 	}
 
-	// Read stream with a size limit to avoid OOM for very large inputs.
-	var buf bytes.Buffer
-	var dest io.Writer
-	if *quiet {
-		dest = &buf
-	} else {
-		dest = io.MultiWriter(os.Stdout, &buf)
+	sorting := *sortFlag || *sortReverse || *sortNumeric || *sortUnique
+	if *shuffle && sorting {
+		fmt.Fprintln(os.Stderr, "--shuffle and --sort are mutually exclusive")
+		os.Exit(1)
+	}
+	if n := countTrue(*encodeB64, *encodeB64URL, *decodeB64URL, *hexFlag, *unhex); n > 1 {
+		fmt.Fprintln(os.Stderr, "--encode-base64, --encode-base64url, --decode-base64url, --hex and --unhex are mutually exclusive")
+		os.Exit(1)
+	}
+	if *upper && *lower {
+		fmt.Fprintln(os.Stderr, "--upper and --lower are mutually exclusive")
+		os.Exit(1)
+	}
+	if *tsvToCSV && *csvToTSV {
+		fmt.Fprintln(os.Stderr, "--tsv-to-csv and --csv-to-tsv are mutually exclusive")
+		os.Exit(1)
 	}
 
-	limited := io.LimitReader(os.Stdin, maxBufferSize)
-	if _, err := io.Copy(dest, limited); err != nil {
+	// Compile line filters up front so a bad pattern fails before reading.
+	var grepRE, grepVRE *regexp.Regexp
+	casePrefix := ""
+	if *ignoreCase {
+		casePrefix = "(?i)"
+	}
+	if *grep != "" {
+		re, err := regexp.Compile(casePrefix + *grep)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "invalid --grep pattern:", err)
+			os.Exit(1)
+		}
+		grepRE = re
+	}
+	if *grepV != "" {
+		re, err := regexp.Compile(casePrefix + *grepV)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "invalid --grep-v pattern:", err)
+			os.Exit(1)
+		}
+		grepVRE = re
+	}
+	if *highlight && grepRE == nil {
+		fmt.Fprintln(os.Stderr, "--highlight needs a --grep pattern")
+		os.Exit(1)
+	}
+	if *countOf != "" && *countOfRegex != "" {
+		fmt.Fprintln(os.Stderr, "--count-of and --count-of-regex are mutually exclusive")
+		os.Exit(1)
+	}
+	var countRE *regexp.Regexp
+	if *countOfRegex != "" {
+		re, err := regexp.Compile(*countOfRegex)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "invalid --count-of-regex pattern:", err)
+			os.Exit(1)
+		}
+		countRE = re
+	}
+	var firstRE *regexp.Regexp
+	if *first != "" {
+		re, err := regexp.Compile(*first)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "invalid --first pattern:", err)
+			os.Exit(1)
+		}
+		firstRE = re
+	}
+	if *sample < 0 {
+		fmt.Fprintln(os.Stderr, "invalid --sample:", *sample, "(want a positive byte count)")
+		os.Exit(1)
+	}
+	var byteSpan span
+	if *byteRange != "" {
+		sp, err := parseSpan(*byteRange)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "invalid --bytes:", err)
+			os.Exit(1)
+		}
+		byteSpan = sp
+	}
+
+	var jsonPath []jsonStep
+	if *jsonField != "" {
+		p, err := parseJSONPath(*jsonField)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "invalid --json-field:", err)
+			os.Exit(1)
+		}
+		jsonPath = p
+	}
+	var lineSel span
+	if *lineRange != "" {
+		sp, err := parseLineSpan(*lineRange)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "invalid --lines:", err)
+			os.Exit(1)
+		}
+		lineSel = sp
+	}
+
+	// The budget covers everything from here: reading, processing and the
+	// clipboard write. Blocked reads and helpers can't be interrupted
+	// portably, so a timer ends the process instead. The timer is stopped
+	// once the copy is done, so the --clear-after wait isn't cut short;
+	// if it fires while a copy that should be cleared is under way, it
+	// clears the clipboard before exiting.
+	var deadlineTimer *time.Timer
+	var deadlineMu sync.Mutex
+	var deadlineCleanup func()
+	if *deadline > 0 {
+		deadlineTimer = time.AfterFunc(*deadline, func() {
+			deadlineMu.Lock() // held until exit
+			fmt.Fprintf(os.Stderr, "deadline error: not finished within %s\n", *deadline)
+			if deadlineCleanup != nil {
+				deadlineCleanup()
+			}
+			os.Exit(exitDeadline)
+		})
+	}
+
+	if _, known := clipboardHelperArgs[*clipboardCmd]; *clipboardCmd != "" && *clipboardCmd != methodOSC52 && !known {
+		fmt.Fprintln(os.Stderr, "invalid --clipboard-cmd:", *clipboardCmd, "(want pbcopy, wl-copy, xclip, xsel or osc52)")
+		os.Exit(1)
+	}
+	if !slices.Contains(pasteboards, *pasteboard) {
+		fmt.Fprintln(os.Stderr, "invalid --pasteboard:", *pasteboard, "(want general, find, font or ruler)")
+		os.Exit(1)
+	}
+	if *pasteboard != "general" && runtime.GOOS != "darwin" {
+		fmt.Fprintln(os.Stderr, "--pasteboard is only available on macOS")
+		os.Exit(1)
+	}
+	clipOpts := clipOptions{
+		noFallback: *noFallback,
+		osc52Both:  *osc52Both,
+		cleanEnv:   *cleanEnv,
+		helper:     *clipboardCmd,
+		strict:     *strictDetect,
+		pasteboard: *pasteboard,
+		tty:        *ttyPath,
+	}
+	if *printMethod {
+		method, err := describeClipboardMethod(clipOpts)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "clipboard error:", err)
+			os.Exit(1)
+		}
+		fmt.Println(method)
+		return
+	}
+
+	// Commands; any other arguments are files to read.
+	switch flag.Arg(0) {
+	case "sync-primary":
+		if err := runSyncPrimary(clipOpts, *quiet, *human); err != nil {
+			fmt.Fprintln(os.Stderr, "sync-primary error:", err)
+			os.Exit(1)
+		}
+		return
+	case "paste":
+		if err := runPaste(flag.Args()[1:]); err != nil {
+			fmt.Fprintln(os.Stderr, "paste error:", err)
+			os.Exit(1)
+		}
+		return
+	case "monitor":
+		if err := runMonitor(flag.Args()[1:]); err != nil {
+			fmt.Fprintln(os.Stderr, "monitor error:", err)
+			os.Exit(1)
+		}
+		return
+	case "snapshot":
+		if err := runSnapshot(flag.Args()[1:], *quiet, *human); err != nil {
+			fmt.Fprintln(os.Stderr, "snapshot error:", err)
+			os.Exit(1)
+		}
+		return
+	}
+	if *watch != "" {
+		throttle := &notifyThrottle{interval: *notifyInterval, send: func(body string) error {
+			return sendNotification(*label, body)
+		}}
+		var metrics watchMetrics
+		updateMetrics := func(n int, err error) {
+			if *metricsFile == "" {
+				return
+			}
+			metrics.record(n, err, time.Now())
+			if err := writeMetrics(*metricsFile, metrics); err != nil {
+				fmt.Fprintln(os.Stderr, "metrics error:", err)
+			}
+		}
+		if *metricsFile != "" {
+			if err := writeMetrics(*metricsFile, metrics); err != nil {
+				fmt.Fprintln(os.Stderr, "metrics error:", err)
+				os.Exit(1)
+			}
+		}
+		err := watchFile(*watch, func(content string) error {
+			if *strip {
+				if *keepSGR {
+					content = stripANSIKeepSGR(content)
+				} else {
+					content = stripANSI(content)
+				}
+			}
+			content = trimText(content, *trimMode)
+			if err := writeToClipboard(content, clipOpts); err != nil {
+				updateMetrics(0, err)
+				return err
+			}
+			updateMetrics(len(content), nil)
+			if !*quiet {
+				fmt.Fprintf(os.Stderr, "Copied %s: %s.\n", *watch, describeSize(content, *human))
+			}
+			if *notify {
+				throttle.notify(time.Now(), "Copied "+*watch)
+			}
+			return nil
+		}, os.Stderr, nil)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "watch error:", err)
+			os.Exit(1)
+		}
+		return
+	}
+	files := flag.Args()
+
+	// --fd stands in for stdin wherever stdin would be read.
+	stdin := os.Stdin
+	if *fd < 0 {
+		fmt.Fprintln(os.Stderr, "invalid --fd:", *fd)
+		os.Exit(1)
+	} else if *fd != 0 {
+		stdin = os.NewFile(uintptr(*fd), fmt.Sprintf("fd %d", *fd))
+	}
+
+	// Ensure stdin is a pipe, file or socket rather than a terminal
+	if len(files) == 0 || slices.Contains(files, "-") {
+		kind, err := classifyInput(stdin)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "error: unable to stat input:", err)
+			os.Exit(1)
+		}
+		if kind == inputTerminal {
+			fmt.Fprintln(os.Stderr, "No piped input detected. Use: some_command |", os.Args[0])
+			fmt.Fprintln(os.Stderr, "Use -h for help and examples.")
+			os.Exit(1)
+		}
+	}
+	in, sources, closeInputs, err := openInputs(stdin, files, *withHeader, *stdinLabel)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+	defer closeInputs()
+
+	start := time.Now()
+
+	// Read stream with a size limit to avoid OOM for very large inputs.
+	cfg := readConfig{in: in, limit: maxBufferSize, wait: *wait}
+	if *sample > 0 {
+		// Read enough to finish a character that straddles the cut.
+		cfg.limit = *sample + utf8.UTFMax - 1
+	}
+	if !*quiet {
+		cfg.echo = os.Stdout
+		if *flushEcho {
+			cfg.echo = syncWriter{f: os.Stdout}
+		}
+		if *echoTimestamp {
+			cfg.echo = &prefixWriter{w: cfg.echo, prefix: timestampPrefix}
+		}
+		if *highlight && grepRE != nil {
+			cfg.echo = &highlightWriter{w: cfg.echo, re: grepRE}
+		}
+	}
+	raw, truncated, err := readInput(cfg)
+	if err != nil {
 		fmt.Fprintln(os.Stderr, "read error:", err)
 		os.Exit(1)
 	}
+	sampled := false
+	if *sample > 0 {
+		sampled = truncated || int64(len(raw)) > *sample
+		raw = truncateBytes(raw, int(*sample))
+		if !*quiet {
+			if sampled {
+				fmt.Fprintf(os.Stderr, "Sample: using the first %s of the input.\n", formatBytes(len(raw), *human))
+			} else {
+				fmt.Fprintf(os.Stderr, "Sample: the input is only %s, so all of it is used.\n", formatBytes(len(raw), *human))
+			}
+		}
+	} else if truncated && *errorOnTruncate {
+		fmt.Fprintf(os.Stderr, "input exceeded %d MB; not copying (--error-on-truncate)\n", maxBufferSize>>20)
+		os.Exit(exitTruncated)
+	} else if truncated {
+		fmt.Fprintf(os.Stderr, "Warning: input exceeded %d MB; only the first %d MB is used.\n",
+			maxBufferSize>>20, maxBufferSize>>20)
+	}
+	if len(sources) > 1 && !*quiet {
+		fmt.Fprintln(os.Stderr, sourceSummary(sources, *human))
+	}
+
+	if *byteRange != "" {
+		lo, hi := byteSpan.clamp(len(raw))
+		raw = raw[lo:hi]
+	}
+
+	if *decodeB64URL {
+		if raw, err = decodeBase64URL(raw); err != nil {
+			fmt.Fprintln(os.Stderr, "decode error:", err)
+			os.Exit(1)
+		}
+	}
+	if *unhex {
+		if raw, err = decodeHex(raw); err != nil {
+			fmt.Fprintln(os.Stderr, "decode error:", err)
+			os.Exit(1)
+		}
+	}
+
+	encoding := *encodeB64 || *encodeB64URL || *hexFlag
+	if !*binaryOK && !encoding && looksBinary([]byte(raw)) {
+		fmt.Fprintln(os.Stderr, "Input looks binary; refusing to copy. Use --binary to copy it as-is, --encode-base64 or --hex.")
+		os.Exit(1)
+	}
+
+	output := raw
+	if *encodeB64 {
+		output = base64.StdEncoding.EncodeToString([]byte(raw))
+	} else if *encodeB64URL {
+		output = base64.URLEncoding.EncodeToString([]byte(raw))
+	} else if *hexFlag {
+		output = hex.EncodeToString([]byte(raw))
+	} else if *strip {
+		if *keepSGR {
+			output = stripANSIKeepSGR(output)
+		} else {
+			output = stripANSI(output)
+		}
+	}
+	// The marker is checked before any filtering so --grep can't hide it.
+	if *requireMarker != "" && !lastLineContains(output, *requireMarker) {
+		fmt.Fprintf(os.Stderr, "Marker %q not found on the last line; not copying.\n", *requireMarker)
+		os.Exit(1)
+	}
+	if *decodeQP {
+		output = decodeQuotedPrintable(output)
+	}
+	if *stripHTMLFlag {
+		output = stripHTML(output)
+	}
+	if *jsonField != "" {
+		if output, err = extractJSON(output, jsonPath); err != nil {
+			fmt.Fprintln(os.Stderr, "json-field error:", err)
+			os.Exit(1)
+		}
+	}
+	if *lineRange != "" {
+		output = lineSpan(output, lineSel)
+	}
+	if *since > 0 {
+		output = filterSince(output, time.Now(), *since, !*sinceDropUntimed)
+	}
+	if *maxLineLen > 0 {
+		output = limitLineLength(output, *maxLineLen, *maxLineMode == "truncate")
+	}
+	if *maxLineBytes > 0 {
+		output = mapLines(output, func(line string) string { return truncateBytes(line, *maxLineBytes) })
+	}
+	var matched, dropped *int
+	if isFlagSet("strip-comments") {
+		prefixes := commentPrefixes(*stripComments)
+		if len(prefixes) == 0 {
+			prefixes = []string{"#"}
+		}
+		output, _ = filterLines(output, func(line string) bool {
+			return !isCommentLine(line, prefixes)
+		})
+	}
+	if grepRE != nil {
+		var n int
+		output, n = grepLines(output, grepRE)
+		matched = &n
+	}
+	if grepVRE != nil {
+		var n int
+		output, n = grepVLines(output, grepVRE)
+		dropped = &n
+	}
+	if firstRE != nil {
+		line, ok := firstMatch(output, firstRE)
+		if !ok {
+			fmt.Fprintf(os.Stderr, "No line matches %q.\n", *first)
+			os.Exit(1)
+		}
+		output = line
+	}
+	if *absPaths {
+		output = mapLines(output, absPath)
+	}
+	if *dedentFlag {
+		output = dedent(output)
+	}
+	if *squeezeWS {
+		output = mapLines(output, squeezeSpaces)
+	}
+	if sorting {
+		output = sortLines(output, sortOptions{
+			reverse:    *sortReverse,
+			numeric:    *sortNumeric,
+			unique:     *sortUnique,
+			ignoreCase: *ignoreCase,
+		})
+	}
+	if *uniq {
+		output = uniqLines(output, *ignoreCase)
+	}
+	if *shuffle {
+		s1, s2 := *seed, *seed
+		if !isFlagSet("seed") {
+			s1, s2 = rand.Uint64(), rand.Uint64()
+		}
+		output = shuffleLines(output, rand.New(rand.NewPCG(s1, s2)))
+	}
+	if *countMatches {
+		if matched != nil {
+			fmt.Fprintf(os.Stderr, "Matched %d lines.\n", *matched)
+		}
+		if dropped != nil {
+			fmt.Fprintf(os.Stderr, "Dropped %d lines.\n", *dropped)
+		}
+	}
+	if *tsvToCSV || *csvToTSV {
+		from, to := '\t', ','
+		if *csvToTSV {
+			from, to = ',', '\t'
+		}
+		if output, err = convertDelimited(output, from, to); err != nil {
+			fmt.Fprintln(os.Stderr, "conversion error:", err)
+			os.Exit(1)
+		}
+	}
+	if *mdTable {
+		output = markdownTable(output)
+	} else if *align {
+		output = alignColumns(output)
+	}
+	if *selectFlag && output != "" {
+		lines, trailingNL := splitLines(output)
+		picked, err := selectLines(lines)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "select error:", err)
+			os.Exit(1)
+		}
+		output = joinLines(picked, trailingNL)
+	}
+	if *envExpand {
+		output = expandEnv(output, *envKeepMissing)
+	}
+	if isFlagSet("join") {
+		sep := unescapeDelim(*join)
+		if sep == "" {
+			sep = " "
+		}
+		output = joinWith(output, sep, *joinSkipBlank)
+	}
+	output = changeCase(output, *upper, *lower)
+	output = trimText(output, *trimMode)
+	if *urlOnly || *urlAll {
+		if urls := findURLs(output, *urlAll); len(urls) > 0 {
+			output = strings.Join(urls, "\n")
+		} else if *urlMissing == "error" {
+			fmt.Fprintln(os.Stderr, "No URL found; not copying.")
+			os.Exit(1)
+		}
+	}
+	if *shellQuoteAll {
+		output = shellQuoteLines(output)
+	} else if *shellQuoteFlag {
+		// A trailing newline ends the command's output; it isn't part of the value.
+		output = shellQuote(strings.TrimSuffix(output, "\n"))
+	}
+	if *truncMiddle > 0 {
+		output = truncateMiddle(output, *truncMiddle)
+	}
+	if *number || isFlagSet("number-start") {
+		output = numberLines(output, *numberStart)
+	}
+	if *codeFlag {
+		tag := *lang
+		if tag == "" {
+			name := *codeFilename
+			if name == "" && len(files) == 1 {
+				name = files[0]
+			}
+			tag = guessLang(name, output)
+		}
+		output = codeBlock(output, tag)
+	}
+
+	if *pipeCmd != "" {
+		if output, err = pipeContent(*pipeCmd, output); err != nil {
+			fmt.Fprintln(os.Stderr, "pipe error:", err)
+			if *pipePreserveExit {
+				os.Exit(pipeExitCode(err))
+			}
+			os.Exit(1)
+		}
+	}
+	if *edit {
+		if output, err = editContent(output); err != nil {
+			fmt.Fprintln(os.Stderr, "edit error:", err)
+			os.Exit(1)
+		}
+	}
 
-	output := buf.String()
-	if *strip {
-		output = stripANSI(output)
+	var occurrences *int
+	if *countOf != "" || countRE != nil {
+		what, n := *countOf, 0
+		if countRE != nil {
+			what, n = countRE.String(), len(countRE.FindAllStringIndex(output, -1))
+		} else {
+			n = strings.Count(output, *countOf)
+		}
+		fmt.Fprintf(os.Stderr, "%d occurrence(s) of %q\n", n, what)
+		occurrences = &n
+	}
+	if *measure {
+		fmt.Fprintf(os.Stderr, "%d bytes, %d runes, %d columns\n",
+			len(output), utf8.RuneCountInString(output), displayWidth(output))
 	}
-	if *trim {
-		output = strings.TrimSpace(output)
+	if *copyCount {
+		output = strconv.Itoa(countContent(output, *countMetric))
+	}
+
+	rep := newReport(output)
+	rep.Label = *label
+	rep.Sampled = sampled
+	rep.Occurrences = occurrences
+	if *secret {
+		rep.Content, rep.Redacted = "", true
+	}
+	if *countMatches {
+		rep.Matched, rep.Dropped = matched, dropped
 	}
 
 	if output == "" {
 		// nothing to do
-		if !*quiet {
+		if *jsonOut {
+			_ = writeReport(os.Stdout, rep)
+		} else if !*quiet {
 			fmt.Fprintln(os.Stderr, "No content to copy.")
 		}
 		return
 	}
 
+	if *diffLast {
+		entries, err := loadHistory()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "history error:", err)
+			os.Exit(1)
+		}
+		if len(entries) == 0 {
+			fmt.Fprintln(os.Stderr, "History is empty; nothing to diff against.")
+			os.Exit(1)
+		}
+		fmt.Print(unifiedDiff("last copy", "input", entries[len(entries)-1].Content, output))
+		return
+	}
+
+	if *warnSecrets {
+		if found := describeSecrets(output); found != "" {
+			fmt.Fprintf(os.Stderr, "Warning: the content may contain secrets: %s.\n", found)
+		}
+	}
+
+	if *preview > 0 {
+		writePreview(os.Stderr, output, *preview)
+	}
+
+	// Encode the QR code before any side effects so oversized content fails
+	// cleanly.
+	var code *qrCode
+	if *qr {
+		if code, err = encodeQR([]byte(output)); err != nil {
+			fmt.Fprintln(os.Stderr, "qr error:", err)
+			os.Exit(1)
+		}
+	}
+
+	// From here on the -f file and the clipboard may get different content.
+	fileOutput, clipOutput := output, output
+	applyTo := func(dest string, fn func(string) string) {
+		if dest != "clipboard" {
+			fileOutput = fn(fileOutput)
+		}
+		if dest != "file" {
+			clipOutput = fn(clipOutput)
+		}
+	}
+	if *expandTabsN > 0 {
+		applyTo(*expandTabsIn, func(s string) string { return expandTabs(s, *expandTabsN) })
+	}
+	if *crlfToLF {
+		applyTo(*crlfToLFIn, func(s string) string { return strings.ReplaceAll(s, "\r\n", "\n") })
+	}
+	if *maxClipBytes > 0 && len(clipOutput) > *maxClipBytes {
+		clipOutput = truncateBytes(clipOutput, *maxClipBytes)
+		if !*quiet {
+			fmt.Fprintf(os.Stderr, "Clipboard copy cut to %s (--max-clipboard-bytes).\n", formatBytes(len(clipOutput), *human))
+		}
+	}
+
 	// Optional file logging
 	if *logFile != "" {
-		if err := writeToFile(*logFile, output, *appendFile); err != nil {
+		var data string
+		if *logFormat == "jsonl" {
+			if data, err = jsonlEntry(start, *label, fileOutput); err != nil {
+				fmt.Fprintln(os.Stderr, "file write error:", err)
+				os.Exit(1)
+			}
+		} else {
+			header := expandFileTemplate(*fileHeader, start, len(fileOutput))
+			footer := expandFileTemplate(*fileFooter, time.Now(), len(fileOutput))
+			data = fileEntry(header, fileOutput, footer)
+		}
+		fileOpts := fileOptions{
+			appendMode:  *appendFile,
+			atomic:      *atomic,
+			mode:        fileModeBits,
+			enforceMode: *enforceMode,
+			mkdir:       *mkdir,
+			dirMode:     dirModeBits,
+		}
+		if *toEncoding != "utf-8" {
+			enc, replaced, err := encodeText(data, *toEncoding, startsFile(*logFile, *appendFile))
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "file write error:", err)
+				os.Exit(1)
+			}
+			if replaced > 0 {
+				fmt.Fprintf(os.Stderr, "Warning: %d character(s) can't be written in %s and were replaced with '?'.\n", replaced, *toEncoding)
+			}
+			data = enc
+		}
+		if *encrypt {
+			enc, err := encryptContent([]byte(data), encTo)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "encrypt error:", err)
+				os.Exit(1)
+			}
+			data = string(enc)
+		}
+		if err := writeToFile(*logFile, data, fileOpts); err != nil {
 			fmt.Fprintln(os.Stderr, "file write error:", err)
 			os.Exit(1)
 		}
 		if !*quiet {
-			fmt.Fprintf(os.Stderr, "Saved %d bytes to %s\n", len(output), *logFile)
+			fmt.Fprintf(os.Stderr, "Saved %s to %s\n", describeSize(fileOutput, *human), *logFile)
 		}
+		rep.File = *logFile
 	}
 
 	// Clipboard copy
-	if !*noClip {
-		if err := writeToClipboard(output); err != nil {
+	if deadlineTimer != nil && *clearAfter > 0 && !*noClip {
+		deadlineMu.Lock()
+		deadlineCleanup = func() {
+			if err := writeToClipboard("", clipOpts); err != nil {
+				fmt.Fprintln(os.Stderr, "clipboard error:", err)
+			}
+		}
+		deadlineMu.Unlock()
+	}
+	var onClipboard string // what was last written, for --clear-after
+	var copyMethod string  // how it was written, for --on-success
+	// failed runs --on-failure once the clipboard write has failed. The
+	// hook's own failure doesn't change the exit status.
+	failed := func(copyErr error) {
+		if *onFailure == "" {
+			return
+		}
+		if err := runHook(*onFailure, "GOCLIP_ERROR="+copyErr.Error()); err != nil {
+			fmt.Fprintln(os.Stderr, "on-failure hook error:", err)
+		}
+	}
+	if *rotate != "" && !*noClip {
+		segs := splitSegments(clipOutput, unescapeDelim(*rotate))
+		write := func(seg string) (err error) {
+			copyMethod, err = writeToClipboardMethod(seg, clipOpts)
+			return err
+		}
+		if err := rotateCopy(segs, *rotateDelay, write, os.Stderr); err != nil {
 			fmt.Fprintln(os.Stderr, "clipboard error:", err)
-			fmt.Fprintln(os.Stderr, "Hint: install wl-clipboard (wl-copy) or xclip/xsel, or use a terminal that supports OSC 52.")
+			failed(err)
 			os.Exit(1)
 		}
-		if !*quiet {
-			fmt.Fprintln(os.Stderr, "Copied to clipboard.")
+		rep.Copied = true
+		if len(segs) > 0 {
+			onClipboard = segs[len(segs)-1]
+		}
+	} else if !*noClip {
+		content := clipOutput
+		if *appendClip {
+			if current, err := readClipboard(false); err != nil {
+				fmt.Fprintln(os.Stderr, "Warning: can't read the current clipboard; copying new content only:", err)
+			} else {
+				content = appendContent(current, clipOutput, unescapeDelim(*appendSep))
+			}
+		}
+		if *compress {
+			if content, err = compressContent(content); err != nil {
+				fmt.Fprintln(os.Stderr, "compress error:", err)
+				os.Exit(1)
+			}
+		}
+		// Reading the clipboard costs a helper run, so only with --skip-identical.
+		identical := false
+		if *skipIdentical {
+			current, err := readClipboard(false)
+			identical = err == nil && current == content
+		}
+		if identical {
+			if !*quiet {
+				fmt.Fprintln(os.Stderr, "Already on the clipboard.")
+			}
+			rep.Copied, rep.Unchanged = true, true
+			onClipboard, copyMethod = content, "unchanged"
+		} else if method, err := writeToClipboardMethod(content, clipOpts); err != nil {
+			fmt.Fprintln(os.Stderr, "clipboard error:", err)
+			failed(err)
+			if !*pastebin {
+				if !errors.Is(err, errAmbiguousHelper) {
+					fmt.Fprintln(os.Stderr, "Hint: install wl-clipboard (wl-copy) or xclip/xsel, or use a terminal that supports OSC 52.")
+				}
+				os.Exit(1)
+			}
+			endpoint, httpMethod := pastebinSettings()
+			link, err := uploadPaste(endpoint, httpMethod, clipOutput)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "pastebin error:", err)
+				os.Exit(1)
+			}
+			if !*jsonOut {
+				fmt.Println(link)
+			}
+			rep.URL = link
+		} else {
+			if !*quiet {
+				fmt.Fprintln(os.Stderr, "Copied to clipboard.")
+			}
+			rep.Copied = true
+			onClipboard, copyMethod = content, method
+			if *cliphist {
+				if err := storeInCliphist(clipOutput, helperEnv(*cleanEnv)); err != nil {
+					fmt.Fprintln(os.Stderr, "cliphist warning:", err)
+				}
+			}
+			if *history {
+				if err := appendHistory(clipOutput, *historyDedup); err != nil {
+					fmt.Fprintln(os.Stderr, "history error:", err)
+				}
+			}
+		}
+	}
+
+	if deadlineTimer != nil {
+		deadlineMu.Lock()
+		deadlineTimer.Stop()
+		deadlineMu.Unlock()
+	}
+
+	if *onSuccess != "" && rep.Copied {
+		err := runHook(*onSuccess, "GOCLIP_BYTES="+strconv.Itoa(len(onClipboard)), "GOCLIP_METHOD="+copyMethod)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "on-success hook error:", err)
+			if *onSuccessFatal {
+				os.Exit(1)
+			}
 		}
 	}
 
 	// Desktop notification (best-effort)
 	if *notify {
-		_ = exec.Command("notify-send", "goclip", "Content copied to clipboard").Run()
+		body := "Content copied to clipboard"
+		if !*secret {
+			if snippet := notificationPreview(clipOutput); snippet != "" {
+				body += "\n" + snippet
+			}
+		}
+		_ = sendNotification(*label, body)
+	}
+
+	if code != nil {
+		_ = renderQR(os.Stderr, code)
+	}
+
+	if *openFlag {
+		if u, ok := singleURL(output); !ok {
+			fmt.Fprintln(os.Stderr, "warning: content is not a single URL; nothing to open")
+		} else if err := openURL(u); err != nil {
+			fmt.Fprintln(os.Stderr, "open error:", err)
+		}
+	}
+
+	// Simulated paste (best-effort)
+	if *pasteInto && rep.Copied {
+		if bin, args, ok := pasteKeystrokeCmd(); !ok {
+			fmt.Fprintln(os.Stderr, "warning: --paste-into needs xdotool (X11) or wtype (Wayland); not pasting")
+		} else if err := exec.Command(bin, args...).Run(); err != nil {
+			fmt.Fprintln(os.Stderr, "paste-into error:", err)
+		}
+	}
+
+	if *jsonOut {
+		if err := writeReport(os.Stdout, rep); err != nil {
+			fmt.Fprintln(os.Stderr, "json error:", err)
+			os.Exit(1)
+		}
+	}
+
+	if *clearAfter > 0 && rep.Copied {
+		if err := clearClipboardAfter(*clearAfter, onClipboard, clipOpts, os.Stderr); err != nil {
+			fmt.Fprintln(os.Stderr, "clipboard error:", err)
+			os.Exit(1)
+		}
 	}
 }
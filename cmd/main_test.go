@@ -0,0 +1,1241 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStripANSI(t *testing.T) {
+	tests := []struct {
+		name, in       string
+		want, wantKeep string
+	}{
+		{"plain", "hello", "hello", "hello"},
+		{"sgr", "\x1b[1;31mred\x1b[0m", "red", "\x1b[1;31mred\x1b[0m"},
+		{"sgr with colon", "\x1b[38:2:255:0:0mx\x1b[m", "x", "\x1b[38:2:255:0:0mx\x1b[m"},
+		{"cursor movement", "a\x1b[2Kb\x1b[1Ac\x1b[?25l", "abc", "abc"},
+		{"osc title bel", "\x1b]0;title\x07text", "text", "text"},
+		{"osc hyperlink st", "\x1b]8;;https://x\x1b\\link\x1b]8;;\x1b\\", "link", "link"},
+		{"dcs string", "\x1bPq#0;2\x1b\\after", "after", "after"},
+		{"charset designation", "\x1b(Bline", "line", "line"},
+		{"mixed", "\x1b[32mok\x1b[0m\x1b[K done\x1b]0;t\x07", "ok done", "\x1b[32mok\x1b[0m done"},
+		{"private csi ending in m kept out", "\x1b[?1m", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := stripANSI(tt.in); got != tt.want {
+				t.Errorf("stripANSI(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+			if got := stripANSIKeepSGR(tt.in); got != tt.wantKeep {
+				t.Errorf("stripANSIKeepSGR(%q) = %q, want %q", tt.in, got, tt.wantKeep)
+			}
+		})
+	}
+}
+
+func TestExpandFileTemplate(t *testing.T) {
+	at := time.Date(2024, 3, 10, 12, 30, 0, 0, time.UTC)
+	tests := []struct {
+		tpl, want string
+	}{
+		{"", ""},
+		{"--- {time} ---", "--- 2024-03-10T12:30:00Z ---"},
+		{"=== {bytes} bytes at {time}", "=== 42 bytes at 2024-03-10T12:30:00Z"},
+		{"{time}{time}", "2024-03-10T12:30:00Z2024-03-10T12:30:00Z"},
+		{"{unknown} {Time}", "{unknown} {Time}"},
+	}
+	for _, tt := range tests {
+		if got := expandFileTemplate(tt.tpl, at, 42); got != tt.want {
+			t.Errorf("expandFileTemplate(%q) = %q, want %q", tt.tpl, got, tt.want)
+		}
+	}
+}
+
+func TestFileEntry(t *testing.T) {
+	tests := []struct {
+		name, header, content, footer, want string
+	}{
+		{"content only", "", "a\n", "", "a\n"},
+		{"header and footer", "BEGIN", "a\nb\n", "END", "BEGIN\na\nb\nEND\n"},
+		{"footer after unterminated content", "", "a", "END", "a\nEND\n"},
+		{"unterminated content without footer", "BEGIN", "a", "", "BEGIN\na"},
+		{"empty content", "BEGIN", "", "END", "BEGIN\nEND\n"},
+	}
+	for _, tt := range tests {
+		if got := fileEntry(tt.header, tt.content, tt.footer); got != tt.want {
+			t.Errorf("%s: fileEntry = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+// TestAppendBracketed appends two runs to one file, as -a with
+// --file-header and --file-footer does, and checks each is bracketed.
+func TestAppendBracketed(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "capture.log")
+	start := time.Date(2024, 3, 10, 9, 0, 0, 0, time.UTC)
+	for i, content := range []string{"first run\n", "second run"} {
+		begin := start.Add(time.Duration(i) * time.Hour)
+		entry := fileEntry(
+			expandFileTemplate("--- start {time}", begin, len(content)),
+			content,
+			expandFileTemplate("--- end {time} ({bytes} bytes)", begin.Add(time.Minute), len(content)),
+		)
+		if err := writeToFile(path, entry, fileOptions{appendMode: true, mode: 0o644}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "--- start 2024-03-10T09:00:00Z\nfirst run\n--- end 2024-03-10T09:01:00Z (10 bytes)\n" +
+		"--- start 2024-03-10T10:00:00Z\nsecond run\n--- end 2024-03-10T10:01:00Z (10 bytes)\n"
+	if string(got) != want {
+		t.Errorf("file =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestNoFallback(t *testing.T) {
+	if isWSL() {
+		t.Skip("clip.exe would be tried under WSL")
+	}
+	tests := []struct {
+		name       string
+		exit       int
+		noFallback bool
+		wantMethod string
+		wantOSC52  bool
+	}{
+		{"helper works", 0, true, "xclip", false},
+		{"helper fails, fallback", 1, false, methodOSC52, true},
+		{"helper fails, no fallback", 1, true, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := isolateClipboard(t)
+			t.Setenv("DISPLAY", ":0")
+			fakeHelper(t, dir, "xclip", tt.exit)
+			tty := filepath.Join(dir, "tty")
+			method, err := writeToClipboardMethod("data", clipOptions{noFallback: tt.noFallback, tty: tty})
+			if tt.wantMethod == "" {
+				if err == nil || !strings.Contains(err.Error(), "xclip") {
+					t.Errorf("err = %v, want the helper's error", err)
+				}
+			} else if err != nil || method != tt.wantMethod {
+				t.Errorf("method = %q, %v; want %q", method, err, tt.wantMethod)
+			}
+			if got := helperInput(dir, "xclip"); got != "data" {
+				t.Errorf("xclip got %q", got)
+			}
+			seq, _ := os.ReadFile(tty)
+			if wrote := len(seq) > 0; wrote != tt.wantOSC52 {
+				t.Errorf("OSC 52 attempted = %v, want %v", wrote, tt.wantOSC52)
+			}
+		})
+	}
+}
+
+func TestOSC52Sequence(t *testing.T) {
+	const enc = "aMOpIPCfmYI=" // base64 of "hé 🙂"
+	tests := []struct {
+		both bool
+		want string
+	}{
+		{false, "\x1b]52;c;" + enc + "\x07"},
+		{true, "\x1b]52;c;" + enc + "\x07" + "\x1b]52;c;" + enc + "\x1b\\"},
+	}
+	for _, tt := range tests {
+		if got := osc52Sequence("hé 🙂", tt.both); got != tt.want {
+			t.Errorf("osc52Sequence(both=%v) = %q, want %q", tt.both, got, tt.want)
+		}
+	}
+}
+
+// TestOSC52Both checks --osc52-both writes the BEL- and ST-terminated
+// sequences once each, and nothing else.
+func TestOSC52Both(t *testing.T) {
+	for _, both := range []bool{false, true} {
+		tty := filepath.Join(t.TempDir(), "tty")
+		if err := os.WriteFile(tty, nil, 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if err := writeClipboardOSC52("copied", clipOptions{tty: tty, osc52Both: both}); err != nil {
+			t.Fatal(err)
+		}
+		b, _ := os.ReadFile(tty)
+		got := string(b)
+		bel := strings.Count(got, "\x1b]52;c;Y29waWVk\x07")
+		st := strings.Count(got, "\x1b]52;c;Y29waWVk\x1b\\")
+		wantST := 0
+		if both {
+			wantST = 1
+		}
+		if bel != 1 || st != wantST || strings.Count(got, "\x1b]52") != 1+wantST {
+			t.Errorf("both=%v: wrote %q", both, got)
+		}
+	}
+}
+
+func TestJSONLEntry(t *testing.T) {
+	ts := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		label, content, want string
+	}{
+		{"", "a<b>\n", `{"ts":"2024-05-01T12:00:00Z","bytes":5,"content":"a<b>\n"}` + "\n"},
+		{"build-output", "ok", `{"ts":"2024-05-01T12:00:00Z","label":"build-output","bytes":2,"content":"ok"}` + "\n"},
+	}
+	for _, tt := range tests {
+		got, err := jsonlEntry(ts, tt.label, tt.content)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("jsonlEntry(%q) = %s, want %s", tt.label, got, tt.want)
+		}
+	}
+}
+
+// TestLogFormatJSONL appends two entries with --log-format jsonl and checks
+// each line of the file parses back to its record.
+func TestLogFormatJSONL(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "log.jsonl")
+	inputs := []string{"first <line>\nwith \"quotes\"\n", "second\ttab"}
+	for _, in := range inputs {
+		if _, stderr, err := runGoclip(t, in, "-q", "--no-clip", "-a", "-f", path, "--log-format", "jsonl", "--label", "build"); err != nil {
+			t.Fatalf("goclip: %v\n%s", err, stderr)
+		}
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	if len(lines) != len(inputs) {
+		t.Fatalf("%d lines, want %d:\n%s", len(lines), len(inputs), data)
+	}
+	for i, line := range lines {
+		var rec logRecord
+		if err := json.Unmarshal([]byte(line), &rec); err != nil {
+			t.Fatalf("line %d %q: %v", i+1, line, err)
+		}
+		if rec.Content != inputs[i] || rec.Bytes != len(inputs[i]) || rec.Label != "build" {
+			t.Errorf("line %d = %+v", i+1, rec)
+		}
+		if _, err := time.Parse(time.RFC3339, rec.TS); err != nil {
+			t.Errorf("line %d: ts %q: %v", i+1, rec.TS, err)
+		}
+	}
+
+	if _, _, err := runGoclip(t, "x", "-q", "--no-clip", "-f", path, "--log-format", "xml"); err == nil {
+		t.Error("--log-format xml: want an error")
+	}
+}
+
+// TestGoclipMain runs goclip itself when started by runGoclip, with the
+// arguments from GOCLIP_TEST_ARGS, and does nothing otherwise.
+func TestGoclipMain(t *testing.T) {
+	if os.Getenv("GOCLIP_TEST_MAIN") != "1" {
+		return
+	}
+	os.Args = append([]string{"goclip"}, strings.Split(os.Getenv("GOCLIP_TEST_ARGS"), "\n")...)
+	main()
+	os.Exit(0) // before the test framework prints to stdout
+}
+
+// goclipCommand returns a command that runs goclip with args in a child
+// process, which inherits the environment.
+func goclipCommand(args ...string) *exec.Cmd {
+	cmd := exec.Command(os.Args[0], "-test.run=^TestGoclipMain$")
+	cmd.Env = append(os.Environ(), "GOCLIP_TEST_MAIN=1", "GOCLIP_TEST_ARGS="+strings.Join(args, "\n"))
+	return cmd
+}
+
+// runGoclip runs goclip with args and stdin and returns its standard output
+// and error.
+func runGoclip(t *testing.T, stdin string, args ...string) (string, string, error) {
+	t.Helper()
+	cmd := goclipCommand(args...)
+	cmd.Stdin = strings.NewReader(stdin)
+	var stdout, stderr strings.Builder
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	err := cmd.Run()
+	return stdout.String(), stderr.String(), err
+}
+
+// TestCleanEnv checks which environment a clipboard helper is run with,
+// using a fake wl-copy that saves it.
+func TestCleanEnv(t *testing.T) {
+	envPath, err := exec.LookPath("env")
+	if err != nil {
+		t.Skip("no env command")
+	}
+	tests := []struct {
+		clean     bool
+		wantToken bool
+	}{
+		{false, true},
+		{true, false},
+	}
+	for _, tt := range tests {
+		dir := isolateClipboard(t)
+		t.Setenv("WAYLAND_DISPLAY", "wayland-0")
+		t.Setenv("XDG_RUNTIME_DIR", "/run/user/1000")
+		t.Setenv("GOCLIP_TEST_TOKEN", "s3cret")
+		fakeHelper(t, dir, "wl-copy", 0)
+		script, _ := os.ReadFile(filepath.Join(dir, "wl-copy"))
+		dump := "'" + envPath + "' > '" + filepath.Join(dir, "wl-copy.env") + "'\n"
+		os.WriteFile(filepath.Join(dir, "wl-copy"), []byte(strings.Replace(string(script), "\n", "\n"+dump, 1)), 0o755)
+
+		if err := writeToClipboard("x", clipOptions{cleanEnv: tt.clean, noFallback: true}); err != nil {
+			t.Fatal(err)
+		}
+		b, err := os.ReadFile(filepath.Join(dir, "wl-copy.env"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		env := string(b)
+		if got := strings.Contains(env, "GOCLIP_TEST_TOKEN=s3cret"); got != tt.wantToken {
+			t.Errorf("clean %v: helper saw the token = %v, want %v", tt.clean, got, tt.wantToken)
+		}
+		for _, v := range []string{"PATH=" + dir, "WAYLAND_DISPLAY=wayland-0", "XDG_RUNTIME_DIR=/run/user/1000"} {
+			if !strings.Contains(env, v+"\n") {
+				t.Errorf("clean %v: helper env lacks %s", tt.clean, v)
+			}
+		}
+		if tt.clean {
+			for line := range strings.Lines(env) {
+				name, _, _ := strings.Cut(line, "=")
+				// The shell itself sets a few variables.
+				if !slices.Contains(helperEnvVars, name) && !slices.Contains([]string{"PWD", "SHLVL", "_", "OLDPWD"}, name) {
+					t.Errorf("clean env passed %s", strings.TrimSpace(line))
+				}
+			}
+		}
+	}
+}
+
+// TestIgnoreCase runs goclip with each feature --ignore-case affects, with
+// and without it.
+func TestIgnoreCase(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		in   string
+		want string
+	}{
+		{"grep", []string{"--grep", "error"}, "Error: a\nok\nerror: b\n", "error: b\n"},
+		{"grep ignoring case", []string{"--grep", "error", "--ignore-case"}, "Error: a\nok\nerror: b\n", "Error: a\nerror: b\n"},
+		{"grep-v", []string{"--grep-v", "debug"}, "DEBUG x\ninfo\n", "DEBUG x\ninfo\n"},
+		{"grep-v ignoring case", []string{"--grep-v", "debug", "--ignore-case"}, "DEBUG x\ninfo\n", "info\n"},
+		{"uniq", []string{"--uniq"}, "Yes\nyes\nno\n", "Yes\nyes\nno\n"},
+		{"uniq ignoring case", []string{"--uniq", "--ignore-case"}, "Yes\nyes\nno\n", "Yes\nno\n"},
+		{"sort", []string{"--sort"}, "b\nA\na\nB\n", "A\nB\na\nb\n"},
+		{"sort ignoring case", []string{"--sort", "--ignore-case"}, "b\nA\na\nB\n", "A\na\nb\nB\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := filepath.Join(t.TempDir(), "out.txt")
+			args := append([]string{"-q", "--no-clip", "-f", out}, tt.args...)
+			if _, stderr, err := runGoclip(t, tt.in, args...); err != nil {
+				t.Fatalf("goclip: %v\n%s", err, stderr)
+			}
+			if got, _ := os.ReadFile(out); string(got) != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestNumberStart checks that --number-start implies --number.
+func TestNumberStart(t *testing.T) {
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"--number"}, "1  a\n2  b\n"},
+		{[]string{"--number-start", "10"}, "10  a\n11  b\n"},
+		{[]string{"--number", "--number-start", "9"}, " 9  a\n10  b\n"},
+	}
+	for _, tt := range tests {
+		out := filepath.Join(t.TempDir(), "out.txt")
+		args := append([]string{"-q", "--no-clip", "-f", out}, tt.args...)
+		if _, stderr, err := runGoclip(t, "a\nb\n", args...); err != nil {
+			t.Fatalf("%v: %v\n%s", tt.args, err, stderr)
+		}
+		if got, _ := os.ReadFile(out); string(got) != tt.want {
+			t.Errorf("%v: got %q, want %q", tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestCopyCount(t *testing.T) {
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"--copy-count"}, "4"},
+		{[]string{"--copy-count", "--count-metric", "words"}, "4"},
+		{[]string{"--copy-count", "--count-metric", "bytes"}, "19"},
+		{[]string{"--copy-count", "--count-metric", "chars"}, "18"},
+	}
+	for _, tt := range tests {
+		out := filepath.Join(t.TempDir(), "out.txt")
+		args := append([]string{"-q", "--no-clip", "-f", out}, tt.args...)
+		if _, stderr, err := runGoclip(t, "one two\nthré\n\nfour", args...); err != nil {
+			t.Fatalf("%v: %v\n%s", tt.args, err, stderr)
+		}
+		if got, _ := os.ReadFile(out); string(got) != tt.want {
+			t.Errorf("%v: got %q, want %q", tt.args, got, tt.want)
+		}
+	}
+	if _, _, err := runGoclip(t, "x", "-q", "--no-clip", "--copy-count", "--count-metric", "pages"); err == nil {
+		t.Error("--count-metric pages: want an error")
+	}
+}
+
+func TestWriteFileAtomic(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing string // "" means no file
+		mode     os.FileMode
+		wantMode os.FileMode
+	}{
+		{"new file", "", 0o600, 0o600},
+		{"replaces content", "old content that is longer\n", 0o644, 0o644},
+		{"keeps the old permissions", "old\n", 0o644, 0o600},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "out.txt")
+			if tt.existing != "" {
+				if err := os.WriteFile(path, []byte(tt.existing), 0o600); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.Chmod(path, tt.wantMode); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := writeToFile(path, "new\n", fileOptions{atomic: true, mode: tt.mode}); err != nil {
+				t.Fatal(err)
+			}
+			if got, _ := os.ReadFile(path); string(got) != "new\n" {
+				t.Errorf("file holds %q, want %q", got, "new\n")
+			}
+			if fi, err := os.Stat(path); err != nil || fi.Mode().Perm() != tt.wantMode {
+				t.Errorf("mode = %v, %v, want %v", fi.Mode().Perm(), err, tt.wantMode)
+			}
+			if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+				t.Errorf("%d files left in the directory, want 1", len(entries))
+			}
+		})
+	}
+}
+
+// TestWriteFileAtomicFailure makes the final rename fail, by putting a
+// non-empty directory where the file should go, and checks that the target
+// is untouched and no temporary file is left behind.
+func TestWriteFileAtomicFailure(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "out.txt")
+	if err := os.MkdirAll(filepath.Join(path, "keep"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	err := writeToFile(path, "new\n", fileOptions{atomic: true, mode: 0o644})
+	if err == nil || !strings.Contains(err.Error(), "replace file") {
+		t.Fatalf("err = %v, want a replace file error", err)
+	}
+	if _, err := os.Stat(filepath.Join(path, "keep")); err != nil {
+		t.Errorf("original was disturbed: %v", err)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("%d entries left in the directory, want 1 (temporary file not removed)", len(entries))
+	}
+}
+
+// TestAtomicAppend checks that --atomic doesn't change -a.
+func TestAtomicAppend(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.txt")
+	if err := os.WriteFile(path, []byte("old\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeToFile(path, "new\n", fileOptions{atomic: true, appendMode: true, mode: 0o644}); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := os.ReadFile(path); string(got) != "old\nnew\n" {
+		t.Errorf("file holds %q", got)
+	}
+}
+
+func TestParseFileMode(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    os.FileMode
+		wantErr bool
+	}{
+		{"0600", 0o600, false},
+		{"644", 0o644, false},
+		{"0", 0, false},
+		{"0777", 0o777, false},
+		{"1777", 0, true},
+		{"0800", 0, true},
+		{"rw-r--r--", 0, true},
+		{"", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := parseFileMode(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("parseFileMode(%q) = %v, %v, want %v (error %v)", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestWriteToFileMode(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing os.FileMode // 0 means no file
+		opts     fileOptions
+		wantMode os.FileMode
+	}{
+		{"new file", 0, fileOptions{mode: 0o600}, 0o600},
+		{"new file appended", 0, fileOptions{mode: 0o640, appendMode: true}, 0o640},
+		{"existing file kept", 0o644, fileOptions{mode: 0o600}, 0o644},
+		{"existing file enforced", 0o644, fileOptions{mode: 0o600, enforceMode: true}, 0o600},
+		{"enforced when appending", 0o644, fileOptions{mode: 0o600, enforceMode: true, appendMode: true}, 0o600},
+		{"atomic new file", 0, fileOptions{mode: 0o600, atomic: true}, 0o600},
+		{"atomic enforced", 0o644, fileOptions{mode: 0o600, atomic: true, enforceMode: true}, 0o600},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "out.txt")
+			if tt.existing != 0 {
+				if err := os.WriteFile(path, nil, 0o600); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.Chmod(path, tt.existing); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := writeToFile(path, "secret\n", tt.opts); err != nil {
+				t.Fatal(err)
+			}
+			fi, err := os.Stat(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := fi.Mode().Perm(); got != tt.wantMode {
+				t.Errorf("mode %v, want %v", got, tt.wantMode)
+			}
+		})
+	}
+}
+
+func TestWriteToFileMkdir(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    fileOptions
+		wantErr string
+	}{
+		{"nested", fileOptions{mode: 0o644, mkdir: true, dirMode: 0o700}, ""},
+		{"nested atomic", fileOptions{mode: 0o644, mkdir: true, dirMode: 0o750, atomic: true}, ""},
+		{"nested append", fileOptions{mode: 0o644, mkdir: true, dirMode: 0o755, appendMode: true}, ""},
+		{"without --mkdir", fileOptions{mode: 0o644}, "open file"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			path := filepath.Join(root, "logs", "today", "out.txt")
+			err := writeToFile(path, "hi\n", tt.opts)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, _ := os.ReadFile(path); string(got) != "hi\n" {
+				t.Errorf("file holds %q", got)
+			}
+			for _, d := range []string{"logs", filepath.Join("logs", "today")} {
+				fi, err := os.Stat(filepath.Join(root, d))
+				if err != nil || fi.Mode().Perm() != tt.opts.dirMode {
+					t.Errorf("%s: mode %v, %v, want %v", d, fi.Mode().Perm(), err, tt.opts.dirMode)
+				}
+			}
+		})
+	}
+}
+
+// TestWriteToFileMkdirExisting checks --mkdir is harmless when the
+// directory is already there, and fails cleanly when a file is in the way.
+func TestWriteToFileMkdirExisting(t *testing.T) {
+	root := t.TempDir()
+	opts := fileOptions{mode: 0o644, mkdir: true, dirMode: 0o755}
+	if err := writeToFile(filepath.Join(root, "out.txt"), "a", opts); err != nil {
+		t.Errorf("existing directory: %v", err)
+	}
+	if err := writeToFile(filepath.Join(root, "out.txt", "x"), "a", opts); err == nil || !strings.Contains(err.Error(), "create directory") {
+		t.Errorf("file in the way: err = %v, want a create directory error", err)
+	}
+}
+
+// TestDeadline feeds goclip a stdin that never ends and checks --deadline
+// stops it with exit status 124, and that a run finishing in time is
+// unaffected.
+func TestDeadline(t *testing.T) {
+	pr, pw, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pw.Close()
+	if _, err := pw.WriteString("partial input\n"); err != nil {
+		t.Fatal(err)
+	}
+	cmd := goclipCommand("-q", "--no-clip", "--deadline", "200ms")
+	cmd.Stdin = pr
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	begin := time.Now()
+	err = cmd.Run()
+	pr.Close()
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) || exitErr.ExitCode() != exitDeadline {
+		t.Fatalf("err = %v, want exit status %d\n%s", err, exitDeadline, stderr.String())
+	}
+	if elapsed := time.Since(begin); elapsed > 5*time.Second {
+		t.Errorf("took %s to give up", elapsed)
+	}
+	if !strings.Contains(stderr.String(), "deadline error: not finished within 200ms") {
+		t.Errorf("stderr %q", stderr.String())
+	}
+
+	out := filepath.Join(t.TempDir(), "out.txt")
+	if _, stderr, err := runGoclip(t, "quick\n", "-q", "--no-clip", "-f", out, "--deadline", "30s"); err != nil {
+		t.Fatalf("in time: %v\n%s", err, stderr)
+	}
+	if got, _ := os.ReadFile(out); string(got) != "quick\n" {
+		t.Errorf("in time: file holds %q", got)
+	}
+}
+
+// TestTrimFlags checks -t is --trim both and an explicit --trim wins.
+func TestTrimFlags(t *testing.T) {
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{nil, "  x  \n"},
+		{[]string{"-t"}, "x"},
+		{[]string{"--trim", "left"}, "x  \n"},
+		{[]string{"--trim", "right"}, "  x"},
+		{[]string{"-t", "--trim", "left"}, "x  \n"},
+		{[]string{"-t", "--trim", "none"}, "  x  \n"},
+	}
+	for _, tt := range tests {
+		out := filepath.Join(t.TempDir(), "out.txt")
+		args := append([]string{"-q", "--no-clip", "-f", out}, tt.args...)
+		if _, stderr, err := runGoclip(t, "  x  \n", args...); err != nil {
+			t.Fatalf("%v: %v\n%s", tt.args, err, stderr)
+		}
+		if got, _ := os.ReadFile(out); string(got) != tt.want {
+			t.Errorf("%v: got %q, want %q", tt.args, got, tt.want)
+		}
+	}
+	if _, _, err := runGoclip(t, "x", "-q", "--no-clip", "--trim", "middle"); err == nil {
+		t.Error("--trim middle: want an error")
+	}
+}
+
+// copyAndLog runs goclip with a fake wl-copy and an -f file and returns
+// what each destination received.
+func copyAndLog(t *testing.T, stdin string, args ...string) (clip, file string) {
+	t.Helper()
+	dir := isolateClipboard(t)
+	t.Setenv("WAYLAND_DISPLAY", "wayland-0")
+	fakeHelper(t, dir, "wl-copy", 0)
+	out := filepath.Join(dir, "out.txt")
+	args = append([]string{"-q", "--no-fallback", "-f", out}, args...)
+	if _, stderr, err := runGoclip(t, stdin, args...); err != nil {
+		t.Fatalf("goclip %v: %v\n%s", args, err, stderr)
+	}
+	b, _ := os.ReadFile(out)
+	return helperInput(dir, "wl-copy"), string(b)
+}
+
+func TestExpandTabsIn(t *testing.T) {
+	const in, expanded = "k\tv\n", "k   v\n"
+	tests := []struct {
+		dest               string
+		wantClip, wantFile string
+	}{
+		{"clipboard", expanded, in},
+		{"file", in, expanded},
+		{"both", expanded, expanded},
+	}
+	for _, tt := range tests {
+		t.Run(tt.dest, func(t *testing.T) {
+			clip, file := copyAndLog(t, in, "--expand-tabs", "4", "--expand-tabs-in", tt.dest)
+			if clip != tt.wantClip || file != tt.wantFile {
+				t.Errorf("clipboard %q, file %q, want %q and %q", clip, file, tt.wantClip, tt.wantFile)
+			}
+		})
+	}
+	if _, _, err := runGoclip(t, "x", "-q", "--no-clip", "--expand-tabs", "4", "--expand-tabs-in", "screen"); err == nil {
+		t.Error("--expand-tabs-in screen: want an error")
+	}
+}
+
+func TestSample(t *testing.T) {
+	tests := []struct {
+		name        string
+		in          string
+		n           string
+		want        string
+		wantMessage string
+		wantSampled bool
+	}{
+		{"smaller than the input", "0123456789\n", "4", "0123", "Sample: using the first 4 bytes of the input.", true},
+		{"larger than the input", "short\n", "100", "short\n", "Sample: the input is only 6 bytes, so all of it is used.", false},
+		{"exactly the input", "abc", "3", "abc", "Sample: the input is only 3 bytes, so all of it is used.", false},
+		{"cut inside a character", "héllo", "2", "h", "Sample: using the first 1 byte of the input.", true},
+		{"ends on a character", "héllo", "3", "hé", "Sample: using the first 3 bytes of the input.", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := filepath.Join(t.TempDir(), "out.txt")
+			_, stderr, err := runGoclip(t, tt.in, "--no-clip", "-f", out, "--sample", tt.n)
+			if err != nil {
+				t.Fatalf("goclip: %v\n%s", err, stderr)
+			}
+			if got, _ := os.ReadFile(out); string(got) != tt.want {
+				t.Errorf("file holds %q, want %q", got, tt.want)
+			}
+			if !strings.Contains(stderr, tt.wantMessage) {
+				t.Errorf("stderr %q, want %q", stderr, tt.wantMessage)
+			}
+
+			stdout, stderr, err := runGoclip(t, tt.in, "--no-clip", "--json", "--sample", tt.n)
+			if err != nil {
+				t.Fatalf("goclip --json: %v\n%s", err, stderr)
+			}
+			var rep report
+			if err := json.Unmarshal([]byte(stdout), &rep); err != nil {
+				t.Fatalf("--json output %q: %v", stdout, err)
+			}
+			if rep.Sampled != tt.wantSampled || rep.Content != tt.want {
+				t.Errorf("report sampled = %v, content %q, want %v, %q", rep.Sampled, rep.Content, tt.wantSampled, tt.want)
+			}
+		})
+	}
+}
+
+func TestStrictDetect(t *testing.T) {
+	tests := []struct {
+		name    string
+		helpers []string
+		wayland bool
+		x11     bool
+		opts    clipOptions
+		want    string // base name of the chosen helper, "" for none
+		wantErr string
+	}{
+		{"xclip and xsel", []string{"xclip", "xsel"}, false, true, clipOptions{}, "xclip", ""},
+		{"xclip and xsel, strict", []string{"xclip", "xsel"}, false, true, clipOptions{strict: true}, "", "several clipboard helpers found (xclip, xsel)"},
+		{"wl-copy and xclip, strict", []string{"wl-copy", "xclip"}, true, true, clipOptions{strict: true}, "", "(wl-copy, xclip)"},
+		{"only xsel, strict", []string{"xsel"}, false, true, clipOptions{strict: true}, "xsel", ""},
+		{"strict with --clipboard-cmd", []string{"xclip", "xsel"}, false, true, clipOptions{strict: true, helper: "xsel"}, "xsel", ""},
+		{"unused helper ignored", []string{"wl-copy", "xclip"}, false, true, clipOptions{strict: true}, "xclip", ""},
+		{"nothing, strict", nil, false, false, clipOptions{strict: true}, "", ""},
+		{"missing --clipboard-cmd", []string{"xclip"}, false, true, clipOptions{helper: "xsel"}, "", "--clipboard-cmd xsel"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := isolateClipboard(t)
+			if tt.wayland {
+				t.Setenv("WAYLAND_DISPLAY", "wayland-0")
+			}
+			if tt.x11 {
+				t.Setenv("DISPLAY", ":0")
+			}
+			for _, h := range tt.helpers {
+				fakeHelper(t, dir, h, 0)
+			}
+			bin, _, ok, err := chooseClipboardCmd(tt.opts)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ok != (tt.want != "") || ok && filepath.Base(bin) != tt.want {
+				t.Errorf("chose %q (found %v), want %q", bin, ok, tt.want)
+			}
+		})
+	}
+}
+
+// TestStrictDetectExit checks goclip fails without copying when
+// --strict-detect finds several helpers, and names them.
+func TestStrictDetectExit(t *testing.T) {
+	dir := isolateClipboard(t)
+	t.Setenv("DISPLAY", ":0")
+	fakeHelper(t, dir, "xclip", 0)
+	fakeHelper(t, dir, "xsel", 0)
+	_, stderr, err := runGoclip(t, "data\n", "-q", "--strict-detect")
+	if err == nil {
+		t.Fatal("want a non-zero exit")
+	}
+	if !strings.Contains(stderr, "xclip, xsel") || !strings.Contains(stderr, "--clipboard-cmd") {
+		t.Errorf("stderr %q", stderr)
+	}
+	if strings.Contains(stderr, "Hint:") {
+		t.Errorf("stderr suggests installing a helper: %q", stderr)
+	}
+	if helperInput(dir, "xclip") != "" || helperInput(dir, "xsel") != "" {
+		t.Error("a helper was run")
+	}
+	if _, stderr, err := runGoclip(t, "data\n", "-q", "--strict-detect", "--clipboard-cmd", "xsel"); err != nil {
+		t.Fatalf("--clipboard-cmd xsel: %v\n%s", err, stderr)
+	}
+	if got := helperInput(dir, "xsel"); got != "data\n" {
+		t.Errorf("xsel got %q", got)
+	}
+}
+
+func TestDescribeClipboardMethod(t *testing.T) {
+	tests := []struct {
+		name    string
+		helpers []string
+		wayland bool
+		x11     bool
+		opts    clipOptions
+		want    string // with DIR for the helper directory
+		wantErr string
+	}{
+		{"wl-copy", []string{"wl-copy"}, true, false, clipOptions{}, "wl-copy: DIR/wl-copy --paste-once", ""},
+		{"xclip", []string{"xclip"}, false, true, clipOptions{}, "xclip: DIR/xclip -selection clipboard", ""},
+		{"xsel", []string{"xsel"}, false, true, clipOptions{}, "xsel: DIR/xsel --clipboard --input", ""},
+		{"--clipboard-cmd", []string{"xclip", "xsel"}, false, true, clipOptions{helper: "xsel"}, "xsel: DIR/xsel --clipboard --input", ""},
+		{"--clipboard-cmd osc52", []string{"xclip"}, false, true, clipOptions{helper: methodOSC52}, methodOSC52, ""},
+		{"no helper", nil, false, false, clipOptions{}, methodOSC52, ""},
+		{"ambiguous", []string{"xclip", "xsel"}, false, true, clipOptions{strict: true}, "", "several clipboard helpers"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := isolateClipboard(t)
+			if tt.wayland {
+				t.Setenv("WAYLAND_DISPLAY", "wayland-0")
+			}
+			if tt.x11 {
+				t.Setenv("DISPLAY", ":0")
+			}
+			for _, h := range tt.helpers {
+				fakeHelper(t, dir, h, 0)
+			}
+			if tt.want == methodOSC52 && tt.opts.helper == "" && isWSL() {
+				t.Skip("clip.exe may be found under WSL")
+			}
+			got, err := describeClipboardMethod(tt.opts)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if want := strings.ReplaceAll(tt.want, "DIR", dir); err != nil || got != want {
+				t.Errorf("got %q, %v, want %q", got, err, want)
+			}
+		})
+	}
+}
+
+// TestPrintMethod checks --print-method reports the helper and exits
+// without reading stdin, which is held open, or copying anything.
+func TestPrintMethod(t *testing.T) {
+	dir := isolateClipboard(t)
+	t.Setenv("DISPLAY", ":0")
+	fakeHelper(t, dir, "xclip", 0)
+	pr, pw, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pw.Close()
+	cmd := goclipCommand("--print-method")
+	cmd.Stdin = pr
+	var stdout, stderr strings.Builder
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	pr.Close()
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("goclip: %v\n%s", err, stderr.String())
+		}
+	case <-time.After(10 * time.Second):
+		cmd.Process.Kill()
+		t.Fatal("--print-method waited for stdin")
+	}
+	if want := "xclip: " + filepath.Join(dir, "xclip") + " -selection clipboard\n"; stdout.String() != want {
+		t.Errorf("printed %q, want %q", stdout.String(), want)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "xclip.args")); err == nil {
+		t.Error("xclip was run")
+	}
+}
+
+// TestFD passes goclip a pipe as file descriptor 3 and checks --fd 3 reads
+// it instead of stdin.
+func TestFD(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("extra file descriptors aren't inherited on Windows")
+	}
+	pr, pw, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := filepath.Join(t.TempDir(), "out.txt")
+	cmd := goclipCommand("-q", "--no-clip", "-f", out, "--fd", "3")
+	cmd.Stdin = strings.NewReader("from stdin\n")
+	cmd.ExtraFiles = []*os.File{pr} // fd 3 in the child
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	pr.Close()
+	pw.WriteString("from fd 3\n")
+	pw.Close()
+	if err := cmd.Wait(); err != nil {
+		t.Fatalf("goclip: %v\n%s", err, stderr.String())
+	}
+	if got, _ := os.ReadFile(out); string(got) != "from fd 3\n" {
+		t.Errorf("file holds %q, want the fd 3 input", got)
+	}
+
+	tests := []struct {
+		fd, wantErr string
+	}{
+		{"-1", "invalid --fd: -1"},
+		{"9", "unable to stat input"},
+	}
+	for _, tt := range tests {
+		_, stderr, err := runGoclip(t, "x", "-q", "--no-clip", "--fd", tt.fd)
+		if err == nil || !strings.Contains(stderr, tt.wantErr) {
+			t.Errorf("--fd %s: err = %v, stderr %q, want %q", tt.fd, err, stderr, tt.wantErr)
+		}
+	}
+}
+
+func TestPasteboard(t *testing.T) {
+	tests := []struct {
+		pasteboard, wantArgs string
+	}{
+		{"", ""},
+		{"general", ""},
+		{"find", "-pboard find"},
+		{"font", "-pboard font"},
+		{"ruler", "-pboard ruler"},
+	}
+	for _, tt := range tests {
+		dir := isolateClipboard(t)
+		fakeHelper(t, dir, "pbcopy", 0)
+		bin, args, ok, err := chooseClipboardCmd(clipOptions{helper: "pbcopy", pasteboard: tt.pasteboard})
+		if err != nil || !ok || filepath.Base(bin) != "pbcopy" {
+			t.Fatalf("%q: chose %q, %v, %v", tt.pasteboard, bin, ok, err)
+		}
+		if got := strings.Join(args, " "); got != tt.wantArgs {
+			t.Errorf("%q: args %q, want %q", tt.pasteboard, got, tt.wantArgs)
+		}
+		if _, err := writeToClipboardMethod("x", clipOptions{helper: "pbcopy", pasteboard: tt.pasteboard}); err != nil {
+			t.Fatal(err)
+		}
+		if got := helperArgs(dir, "pbcopy"); got != tt.wantArgs {
+			t.Errorf("%q: pbcopy ran with %q, want %q", tt.pasteboard, got, tt.wantArgs)
+		}
+	}
+	if args := clipboardHelperArgs["pbcopy"]; len(args) != 0 {
+		t.Errorf("pbcopy's default args changed to %q", args)
+	}
+}
+
+func TestPasteboardFlag(t *testing.T) {
+	tests := []struct {
+		pasteboard, wantErr string
+		onlyOffMac          bool
+	}{
+		{"clipboard", "invalid --pasteboard: clipboard", false},
+		{"find", "only available on macOS", true},
+	}
+	for _, tt := range tests {
+		if tt.onlyOffMac && runtime.GOOS == "darwin" {
+			continue
+		}
+		_, stderr, err := runGoclip(t, "x", "-q", "--no-clip", "--pasteboard", tt.pasteboard)
+		if err == nil || !strings.Contains(stderr, tt.wantErr) {
+			t.Errorf("--pasteboard %s: err = %v, stderr %q, want %q", tt.pasteboard, err, stderr, tt.wantErr)
+		}
+	}
+}
+
+func TestCountOf(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      string
+		args    []string
+		want    int
+		wantMsg string
+	}{
+		{"literal", "error a\nok\nerror b\n", []string{"--count-of", "error"}, 2, `2 occurrence(s) of "error"`},
+		{"non-overlapping", "aaaa", []string{"--count-of", "aa"}, 2, `2 occurrence(s) of "aa"`},
+		{"odd run", "aaaaa", []string{"--count-of", "aa"}, 2, `2 occurrence(s) of "aa"`},
+		{"none", "abc", []string{"--count-of", "z"}, 0, `0 occurrence(s) of "z"`},
+		{"case matters", "Go go GO", []string{"--count-of", "go"}, 1, `1 occurrence(s) of "go"`},
+		{"regex", "id=1 id=22 x=3", []string{"--count-of-regex", `id=\d+`}, 2, `2 occurrence(s) of "id=\\d+"`},
+		{"regex non-overlapping", "aaaa", []string{"--count-of-regex", "aa"}, 2, `2 occurrence(s) of "aa"`},
+		{"regex greedy", "aaaa", []string{"--count-of-regex", "a+"}, 1, `1 occurrence(s) of "a+"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := append([]string{"--no-clip", "--json"}, tt.args...)
+			stdout, stderr, err := runGoclip(t, tt.in, args...)
+			if err != nil {
+				t.Fatalf("goclip: %v\n%s", err, stderr)
+			}
+			if !strings.Contains(stderr, tt.wantMsg) {
+				t.Errorf("stderr %q, want %q", stderr, tt.wantMsg)
+			}
+			var rep report
+			if err := json.Unmarshal([]byte(stdout), &rep); err != nil {
+				t.Fatalf("--json output %q: %v", stdout, err)
+			}
+			if rep.Occurrences == nil || *rep.Occurrences != tt.want {
+				t.Errorf("occurrences = %v, want %d", rep.Occurrences, tt.want)
+			}
+			if rep.Content != tt.in {
+				t.Errorf("content changed to %q", rep.Content)
+			}
+		})
+	}
+
+	for _, args := range [][]string{
+		{"--count-of", "a", "--count-of-regex", "a"},
+		{"--count-of-regex", "("},
+	} {
+		if _, _, err := runGoclip(t, "a", append([]string{"-q", "--no-clip"}, args...)...); err == nil {
+			t.Errorf("%v: want an error", args)
+		}
+	}
+}
+
+func TestCRLFToLFIn(t *testing.T) {
+	const in, lf = "one\r\ntwo\r\nbare\rend\r\n", "one\ntwo\nbare\rend\n"
+	tests := []struct {
+		args               []string
+		wantClip, wantFile string
+	}{
+		{nil, in, in},
+		{[]string{"--crlf-to-lf"}, lf, in},
+		{[]string{"--crlf-to-lf", "--crlf-to-lf-in", "clipboard"}, lf, in},
+		{[]string{"--crlf-to-lf", "--crlf-to-lf-in", "file"}, in, lf},
+		{[]string{"--crlf-to-lf", "--crlf-to-lf-in", "both"}, lf, lf},
+	}
+	for _, tt := range tests {
+		clip, file := copyAndLog(t, in, tt.args...)
+		if clip != tt.wantClip || file != tt.wantFile {
+			t.Errorf("%v: clipboard %q, file %q, want %q and %q", tt.args, clip, file, tt.wantClip, tt.wantFile)
+		}
+	}
+}
+
+func TestMaxClipboardBytes(t *testing.T) {
+	const in = "héllo wörld\n"
+	tests := []struct {
+		args     []string
+		wantClip string
+	}{
+		{nil, in},
+		{[]string{"--max-clipboard-bytes", "5"}, "héll"},
+		{[]string{"--max-clipboard-bytes", "2"}, "h"}, // not half of é
+		{[]string{"--max-clipboard-bytes", "3"}, "hé"},
+		{[]string{"--max-clipboard-bytes", "100"}, in},
+		{[]string{"--max-clipboard-bytes", "14"}, in},
+	}
+	for _, tt := range tests {
+		clip, file := copyAndLog(t, in, tt.args...)
+		if clip != tt.wantClip {
+			t.Errorf("%v: clipboard %q, want %q", tt.args, clip, tt.wantClip)
+		}
+		if file != in {
+			t.Errorf("%v: file %q, want the full input", tt.args, file)
+		}
+	}
+
+	dir := isolateClipboard(t)
+	t.Setenv("WAYLAND_DISPLAY", "wayland-0")
+	fakeHelper(t, dir, "wl-copy", 0)
+	_, stderr, err := runGoclip(t, in, "--no-fallback", "--max-clipboard-bytes", "5")
+	if err != nil {
+		t.Fatalf("goclip: %v\n%s", err, stderr)
+	}
+	if !strings.Contains(stderr, "Clipboard copy cut to 5 bytes (--max-clipboard-bytes).") {
+		t.Errorf("stderr %q", stderr)
+	}
+}
+
+// TestErrorOnTruncate feeds goclip input at and just over the size limit.
+func TestErrorOnTruncate(t *testing.T) {
+	atLimit := strings.Repeat("x", maxBufferSize)
+	tests := []struct {
+		name       string
+		in         string
+		args       []string
+		wantStatus int
+		wantFile   int // bytes written to -f, -1 for none
+		wantMsg    string
+	}{
+		{"at the limit", atLimit, []string{"--error-on-truncate"}, 0, maxBufferSize, ""},
+		{"over the limit", atLimit + "y", []string{"--error-on-truncate"}, exitTruncated, -1, "not copying (--error-on-truncate)"},
+		{"over the limit, warning only", atLimit + "y", nil, 0, maxBufferSize, "Warning: input exceeded 10 MB"},
+		{"over the limit with --sample", atLimit + "y", []string{"--error-on-truncate", "--sample", "4"}, 0, 4, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := filepath.Join(t.TempDir(), "out.txt")
+			args := append([]string{"-q", "--no-clip", "-f", out}, tt.args...)
+			_, stderr, err := runGoclip(t, tt.in, args...)
+			if got := exitStatus(t, err); got != tt.wantStatus {
+				t.Errorf("exit status %d, want %d\n%s", got, tt.wantStatus, stderr)
+			}
+			fi, statErr := os.Stat(out)
+			switch {
+			case tt.wantFile < 0 && statErr == nil:
+				t.Errorf("-f file written (%d bytes)", fi.Size())
+			case tt.wantFile >= 0 && (statErr != nil || fi.Size() != int64(tt.wantFile)):
+				t.Errorf("-f file: %v, want %d bytes", statErr, tt.wantFile)
+			}
+			if !strings.Contains(stderr, tt.wantMsg) {
+				t.Errorf("stderr %q, want %q", stderr, tt.wantMsg)
+			}
+		})
+	}
+}
+
+// TestTTYPath copies with --clipboard-cmd osc52 --tty pointing at a file and
+// checks the sequence decodes back to the content.
+func TestTTYPath(t *testing.T) {
+	tests := []struct {
+		in   string
+		both bool
+	}{
+		{"héllo 🙂\n", false},
+		{"two\nlines", true},
+	}
+	for _, tt := range tests {
+		tty := filepath.Join(t.TempDir(), "tty")
+		if err := os.WriteFile(tty, []byte("before\n"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		args := []string{"-q", "--clipboard-cmd", "osc52", "--tty", tty, "--osc52-both=" + strconv.FormatBool(tt.both)}
+		if _, stderr, err := runGoclip(t, tt.in, args...); err != nil {
+			t.Fatalf("%q: %v\n%s", tt.in, err, stderr)
+		}
+		b, _ := os.ReadFile(tty)
+		seq, ok := strings.CutPrefix(string(b), "before\n")
+		if !ok {
+			t.Fatalf("%q: earlier content overwritten: %q", tt.in, b)
+		}
+		body, ok := strings.CutPrefix(seq, "\x1b]52;c;")
+		enc, _, found := strings.Cut(body, "\x07")
+		if !ok || !found {
+			t.Fatalf("%q: wrote %q, want an OSC 52 sequence", tt.in, seq)
+		}
+		if got, err := base64.StdEncoding.DecodeString(enc); err != nil || string(got) != tt.in {
+			t.Errorf("%q: sequence decodes to %q, %v", tt.in, got, err)
+		}
+		if want := osc52Sequence(tt.in, tt.both); seq != want {
+			t.Errorf("%q: wrote %q, want %q", tt.in, seq, want)
+		}
+	}
+
+	missing := filepath.Join(t.TempDir(), "no-such-tty")
+	_, stderr, err := runGoclip(t, "x", "-q", "--clipboard-cmd", "osc52", "--tty", missing)
+	if err == nil || !strings.Contains(stderr, "write OSC52") {
+		t.Errorf("missing --tty: err = %v, stderr %q", err, stderr)
+	}
+	if _, err := os.Stat(missing); err == nil {
+		t.Error("--tty created a file")
+	}
+}
+
+func TestJoinFlag(t *testing.T) {
+	const in = "a\nb\n\nc\n"
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{nil, in},
+		{[]string{"--join", ","}, "a,b,,c"},
+		{[]string{"--join", `\t`}, "a\tb\t\tc"},
+		{[]string{"--join", ""}, "a b  c"},
+		{[]string{"--join=", "--join-skip-blank"}, "a b c"},
+		{[]string{"--join-skip-blank"}, in},
+	}
+	for _, tt := range tests {
+		out := filepath.Join(t.TempDir(), "out.txt")
+		args := append([]string{"-q", "--no-clip", "-f", out}, tt.args...)
+		if _, stderr, err := runGoclip(t, in, args...); err != nil {
+			t.Fatalf("%v: %v\n%s", tt.args, err, stderr)
+		}
+		if got, _ := os.ReadFile(out); string(got) != tt.want {
+			t.Errorf("%v: got %q, want %q", tt.args, got, tt.want)
+		}
+	}
+	if _, _, err := runGoclip(t, in, "-q", "--no-clip", "--join-lines"); err == nil {
+		t.Error("--join-lines: want an unknown flag error")
+	}
+}
+
+func TestStripCommentsFlag(t *testing.T) {
+	const in = "# hash\ncode # kept\n  // slashes\n; semi\nend\n"
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{nil, in},
+		{[]string{"--strip-comments", ""}, "code # kept\n  // slashes\n; semi\nend\n"},
+		{[]string{"--strip-comments", "//,;"}, "# hash\ncode # kept\nend\n"},
+		{[]string{"--strip-comments", "#,//"}, "code # kept\n; semi\nend\n"},
+	}
+	for _, tt := range tests {
+		out := filepath.Join(t.TempDir(), "out.txt")
+		args := append([]string{"-q", "--no-clip", "-f", out}, tt.args...)
+		if _, stderr, err := runGoclip(t, in, args...); err != nil {
+			t.Fatalf("%v: %v\n%s", tt.args, err, stderr)
+		}
+		if got, _ := os.ReadFile(out); string(got) != tt.want {
+			t.Errorf("%v: got %q, want %q", tt.args, got, tt.want)
+		}
+	}
+	if _, _, err := runGoclip(t, in, "-q", "--no-clip", "--strip-hash-comments"); err == nil {
+		t.Error("--strip-hash-comments: want an unknown flag error")
+	}
+}
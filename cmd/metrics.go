@@ -0,0 +1,38 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// watchMetrics are the counters --metrics-file reports for a --watch-file
+// session.
+type watchMetrics struct {
+	Copies   int       `json:"copies"`
+	Bytes    int64     `json:"bytes"`
+	Errors   int       `json:"errors"`
+	LastCopy time.Time `json:"last_copy,omitzero"`
+}
+
+// record counts one copy attempt of n bytes made at t that failed with err,
+// if not nil.
+func (m *watchMetrics) record(n int, err error, t time.Time) {
+	if err != nil {
+		m.Errors++
+		return
+	}
+	m.Copies++
+	m.Bytes += int64(n)
+	m.LastCopy = t
+}
+
+// writeMetrics replaces the file at path with m as JSON, via a temporary
+// file and rename so readers never see it half-written.
+func writeMetrics(path string, m watchMetrics) error {
+	b, err := json.Marshal(m)
+	if err != nil {
+		return fmt.Errorf("encode metrics: %w", err)
+	}
+	return writeToFile(path, string(b)+"\n", fileOptions{atomic: true, mode: 0o644})
+}
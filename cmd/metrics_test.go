@@ -0,0 +1,79 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWatchMetricsRecord(t *testing.T) {
+	t0 := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	tests := []struct {
+		name  string
+		calls []struct {
+			n   int
+			err error
+		}
+		want watchMetrics
+	}{
+		{"none", nil, watchMetrics{}},
+		{"copies add up", []struct {
+			n   int
+			err error
+		}{{3, nil}, {5, nil}}, watchMetrics{Copies: 2, Bytes: 8, LastCopy: t0.Add(time.Second)}},
+		{"errors don't count as copies", []struct {
+			n   int
+			err error
+		}{{3, nil}, {0, errors.New("boom")}}, watchMetrics{Copies: 1, Bytes: 3, Errors: 1, LastCopy: t0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var m watchMetrics
+			for i, c := range tt.calls {
+				m.record(c.n, c.err, t0.Add(time.Duration(i)*time.Second))
+			}
+			if m != tt.want {
+				t.Errorf("got %+v, want %+v", m, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteMetricsJSON(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "metrics.json")
+	t0 := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	tests := []struct {
+		name string
+		m    watchMetrics
+		want string
+	}{
+		{"empty omits last_copy", watchMetrics{}, `{"copies":0,"bytes":0,"errors":0}` + "\n"},
+		{"full", watchMetrics{Copies: 2, Bytes: 8, Errors: 1, LastCopy: t0},
+			`{"copies":2,"bytes":8,"errors":1,"last_copy":"2026-01-02T03:04:05Z"}` + "\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := writeMetrics(path, tt.m); err != nil {
+				t.Fatal(err)
+			}
+			b, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(b) != tt.want {
+				t.Errorf("got %q, want %q", b, tt.want)
+			}
+			var back watchMetrics
+			if err := json.Unmarshal(b, &back); err != nil || back != tt.m {
+				t.Errorf("round trip: %+v, %v", back, err)
+			}
+		})
+	}
+	entries, _ := os.ReadDir(filepath.Dir(path))
+	if len(entries) != 1 {
+		t.Errorf("temp files left behind: %v", entries)
+	}
+}
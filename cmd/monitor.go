@@ -0,0 +1,72 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// formatMonitorEntry renders one clipboard change for the monitor log.
+func formatMonitorEntry(t time.Time, content string) string {
+	if !strings.HasSuffix(content, "\n") {
+		content += "\n"
+	}
+	return fmt.Sprintf("==> %s <==\n%s", t.Format(time.RFC3339), content)
+}
+
+// monitorClipboard polls read every interval and writes each new value to
+// w. Consecutive identical values and an empty clipboard are skipped, and a
+// read error is reported once until reading works again. It runs until the
+// process is interrupted.
+func monitorClipboard(read func() (string, error), interval time.Duration, w, status io.Writer) error {
+	var last string
+	var failing bool
+	for {
+		content, err := read()
+		switch {
+		case err != nil:
+			if !failing {
+				fmt.Fprintln(status, "monitor:", err)
+			}
+			failing = true
+		case content != "" && content != last:
+			failing = false
+			last = content
+			if _, err := io.WriteString(w, formatMonitorEntry(time.Now(), content)); err != nil {
+				return err
+			}
+		default:
+			failing = false
+		}
+		time.Sleep(interval)
+	}
+}
+
+// runMonitor implements the monitor command: log every new clipboard value,
+// to stdout or appended to --log.
+func runMonitor(args []string) error {
+	fs := flag.NewFlagSet("monitor", flag.ExitOnError)
+	logPath := fs.String("log", "", "append changes to this file instead of printing them")
+	interval := fs.Duration("interval", time.Second, "how often to check the clipboard")
+	primary := fs.Bool("primary", false, "watch the primary selection instead of the clipboard")
+	_ = fs.Parse(args)
+	if *interval <= 0 {
+		return fmt.Errorf("--interval must be positive")
+	}
+
+	var w io.Writer = os.Stdout
+	if *logPath != "" {
+		// The log collects everything copied, passwords included.
+		f, err := os.OpenFile(*logPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	read := func() (string, error) { return readClipboard(*primary) }
+	return monitorClipboard(read, *interval, w, os.Stderr)
+}
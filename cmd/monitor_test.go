@@ -0,0 +1,106 @@
+package main
+
+import (
+	"errors"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFormatMonitorEntry(t *testing.T) {
+	at := time.Date(2024, 2, 3, 4, 5, 6, 0, time.UTC)
+	tests := []struct{ content, want string }{
+		{"copied\n", "==> 2024-02-03T04:05:06Z <==\ncopied\n"},
+		{"no newline", "==> 2024-02-03T04:05:06Z <==\nno newline\n"},
+		{"a\nb", "==> 2024-02-03T04:05:06Z <==\na\nb\n"},
+	}
+	for _, tt := range tests {
+		if got := formatMonitorEntry(at, tt.content); got != tt.want {
+			t.Errorf("formatMonitorEntry(%q) = %q, want %q", tt.content, got, tt.want)
+		}
+	}
+}
+
+// errStopMonitor ends monitorClipboard in tests: the log writer returns it
+// when asked to log stopMarker.
+var errStopMonitor = errors.New("stop")
+
+const stopMarker = "<stop>"
+
+// monitorLog records the values logged by monitorClipboard.
+type monitorLog struct{ values []string }
+
+var monitorHeader = regexp.MustCompile(`^==> \d{4}-\d\d-\d\dT[^ ]+ <==\n`)
+
+func (l *monitorLog) Write(p []byte) (int, error) {
+	s := string(p)
+	if !monitorHeader.MatchString(s) {
+		return 0, errors.New("entry without a timestamp header: " + s)
+	}
+	s = monitorHeader.ReplaceAllString(s, "")
+	if s == stopMarker+"\n" {
+		return 0, errStopMonitor
+	}
+	l.values = append(l.values, s)
+	return len(p), nil
+}
+
+// TestMonitorClipboard drives monitorClipboard with a sequence of clipboard
+// values through its read function.
+func TestMonitorClipboard(t *testing.T) {
+	errRead := errors.New("wl-paste failed")
+	type value struct {
+		s   string
+		err error
+	}
+	tests := []struct {
+		name       string
+		seq        []value
+		want       []string
+		wantErrors int
+	}{
+		{"each change logged",
+			[]value{{s: "a"}, {s: "b"}, {s: "c\n"}},
+			[]string{"a\n", "b\n", "c\n"}, 0},
+		{"consecutive duplicates skipped",
+			[]value{{s: "a"}, {s: "a"}, {s: "a"}, {s: "b"}, {s: "b"}},
+			[]string{"a\n", "b\n"}, 0},
+		{"value coming back is logged again",
+			[]value{{s: "a"}, {s: "b"}, {s: "a"}},
+			[]string{"a\n", "b\n", "a\n"}, 0},
+		{"empty clipboard skipped",
+			[]value{{s: ""}, {s: "a"}, {s: ""}, {s: "a"}},
+			[]string{"a\n"}, 0},
+		{"read errors reported once per outage",
+			[]value{{s: "a"}, {err: errRead}, {err: errRead}, {s: "a"}, {err: errRead}, {s: "b"}},
+			[]string{"a\n", "b\n"}, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			seq := append(tt.seq, value{s: stopMarker})
+			reads := 0
+			read := func() (string, error) {
+				v := seq[min(reads, len(seq)-1)]
+				reads++
+				return v.s, v.err
+			}
+			var log monitorLog
+			var status strings.Builder
+			err := monitorClipboard(read, time.Millisecond, &log, &status)
+			if !errors.Is(err, errStopMonitor) {
+				t.Fatalf("err = %v", err)
+			}
+			if reads != len(seq) {
+				t.Errorf("read %d times, want %d", reads, len(seq))
+			}
+			if !slices.Equal(log.values, tt.want) {
+				t.Errorf("logged %q, want %q", log.values, tt.want)
+			}
+			if n := strings.Count(status.String(), "monitor: wl-paste failed\n"); n != tt.wantErrors {
+				t.Errorf("reported %d errors (%q), want %d", n, status.String(), tt.wantErrors)
+			}
+		})
+	}
+}
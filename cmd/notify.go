@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// notificationTitle returns the desktop notification title, tagged with
+// label if one is set.
+func notificationTitle(label string) string {
+	if label == "" {
+		return "goclip"
+	}
+	return "goclip: " + label
+}
+
+// sendNotification shows a desktop notification via notify-send.
+func sendNotification(label, body string) error {
+	return exec.Command("notify-send", notificationTitle(label), body).Run()
+}
+
+// notifyPreviewWidth is the column limit for the snippet in a notification.
+const notifyPreviewWidth = 80
+
+// notificationPreview returns the first non-blank line of content as a
+// notification snippet, shortened to notifyPreviewWidth. Escape sequences
+// are always removed, whatever -s says, since notification daemons show
+// them raw.
+func notificationPreview(content string) string {
+	lines, _ := splitLines(stripANSI(content))
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if displayWidth(line) > notifyPreviewWidth {
+			line = headWidth(line, notifyPreviewWidth-1) + "…"
+		}
+		return line
+	}
+	return ""
+}
+
+// notifyThrottle sends at most one notification per interval. Notifications
+// that arrive too soon are counted and mentioned in the next one sent. If
+// none follows, the last of them is sent with the count once the interval
+// is up, so a burst that ends mid-interval is still reported.
+type notifyThrottle struct {
+	interval time.Duration
+	send     func(body string) error
+
+	mu         sync.Mutex
+	last       time.Time
+	suppressed int
+	pending    string      // body of the latest suppressed notification
+	timer      *time.Timer // runs flush at the end of the interval
+}
+
+// notify sends body now if the interval has passed since the last
+// notification, and otherwise holds it back for flush.
+func (t *notifyThrottle) notify(now time.Time, body string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.last.IsZero() && now.Sub(t.last) < t.interval {
+		t.suppressed++
+		t.pending = body
+		if t.timer == nil {
+			t.timer = time.AfterFunc(t.last.Add(t.interval).Sub(now), t.flush)
+		}
+		return
+	}
+	t.sendLocked(now, body)
+}
+
+// flush sends the latest held-back notification, if any, with a count of
+// the others. It runs when the interval after a suppressed one is up.
+func (t *notifyThrottle) flush() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.suppressed == 0 {
+		return
+	}
+	t.suppressed--
+	t.sendLocked(t.last.Add(t.interval), t.pending)
+}
+
+func (t *notifyThrottle) sendLocked(now time.Time, body string) {
+	if t.timer != nil {
+		t.timer.Stop()
+		t.timer = nil
+	}
+	if t.suppressed > 0 {
+		body += fmt.Sprintf(" (%d more since the last notification)", t.suppressed)
+	}
+	t.last, t.suppressed, t.pending = now, 0, ""
+	_ = t.send(body)
+}
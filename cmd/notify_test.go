@@ -0,0 +1,155 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNotificationTitle(t *testing.T) {
+	tests := []struct{ label, want string }{
+		{"", "goclip"},
+		{"build-output", "goclip: build-output"},
+		{"tests 🙂", "goclip: tests 🙂"},
+	}
+	for _, tt := range tests {
+		if got := notificationTitle(tt.label); got != tt.want {
+			t.Errorf("notificationTitle(%q) = %q, want %q", tt.label, got, tt.want)
+		}
+	}
+}
+
+func TestSendNotificationLabel(t *testing.T) {
+	tests := []struct{ label, wantArgs string }{
+		{"", "goclip Copied 3 bytes"},
+		{"build-output", "goclip: build-output Copied 3 bytes"},
+	}
+	for _, tt := range tests {
+		dir := isolateClipboard(t)
+		fakeHelper(t, dir, "notify-send", 0)
+		if err := sendNotification(tt.label, "Copied 3 bytes"); err != nil {
+			t.Fatal(err)
+		}
+		if got := helperArgs(dir, "notify-send"); got != tt.wantArgs {
+			t.Errorf("label %q: notify-send %q, want %q", tt.label, got, tt.wantArgs)
+		}
+	}
+}
+
+func TestNotificationPreview(t *testing.T) {
+	long := strings.Repeat("x", notifyPreviewWidth+10)
+	tests := []struct {
+		name, in, want string
+	}{
+		{"first line", "one\ntwo\n", "one"},
+		{"skips blank lines", "\n  \n\tsecond  \n", "second"},
+		{"colour codes", "\x1b[1;31merror:\x1b[0m failed\n", "error: failed"},
+		{"cursor movement", "\x1b[2K\x1b[1Gprogress 100%\n", "progress 100%"},
+		{"only escapes on the first line", "\x1b[0m\nreal\n", "real"},
+		{"long line", long, strings.Repeat("x", notifyPreviewWidth-1) + "…"},
+		{"empty", "", ""},
+	}
+	for _, tt := range tests {
+		if got := notificationPreview(tt.in); got != tt.want {
+			t.Errorf("%s: notificationPreview(%q) = %q, want %q", tt.name, tt.in, got, tt.want)
+		}
+	}
+}
+
+// TestNotificationStripsANSI copies coloured text with -s=false and checks
+// the clipboard keeps the escapes while the notification doesn't.
+func TestNotificationStripsANSI(t *testing.T) {
+	tests := []struct {
+		args     []string
+		wantArgs string
+		wantRaw  bool // the clipboard keeps the escapes
+	}{
+		{[]string{"-s=false"}, "goclip Content copied to clipboard\nerror: failed", true},
+		{nil, "goclip Content copied to clipboard\nerror: failed", false},
+		{[]string{"-s=false", "--secret", "--clear-after", "0"}, "goclip Content copied to clipboard", true},
+	}
+	for _, tt := range tests {
+		dir := isolateClipboard(t)
+		t.Setenv("WAYLAND_DISPLAY", "wayland-0")
+		fakeHelper(t, dir, "wl-copy", 0)
+		fakeHelper(t, dir, "notify-send", 0)
+		in := "\x1b[31merror:\x1b[0m failed\n"
+		args := append([]string{"-q", "--no-fallback", "-n"}, tt.args...)
+		if _, stderr, err := runGoclip(t, in, args...); err != nil {
+			t.Fatalf("%v: %v\n%s", tt.args, err, stderr)
+		}
+		if got := helperArgs(dir, "notify-send"); got != tt.wantArgs {
+			t.Errorf("%v: notify-send %q, want %q", tt.args, got, tt.wantArgs)
+		}
+		if raw := strings.Contains(helperInput(dir, "wl-copy"), "\x1b[31m"); raw != tt.wantRaw {
+			t.Errorf("%v: clipboard %q", tt.args, helperInput(dir, "wl-copy"))
+		}
+	}
+}
+
+func TestNotifyThrottle(t *testing.T) {
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		interval time.Duration
+		at       []time.Duration // offsets from start of each notification
+		want     []string
+	}{
+		{"burst", time.Second, []time.Duration{0, 10 * time.Millisecond, 20 * time.Millisecond, 30 * time.Millisecond},
+			[]string{"n0", "n3 (2 more since the last notification)"}},
+		{"one held back", time.Second, []time.Duration{0, 10 * time.Millisecond},
+			[]string{"n0", "n1"}},
+		{"burst then a later one", time.Second, []time.Duration{0, 100 * time.Millisecond, 200 * time.Millisecond, 1500 * time.Millisecond},
+			[]string{"n0", "n3 (2 more since the last notification)"}},
+		{"spaced out", time.Second, []time.Duration{0, time.Second, 2 * time.Second},
+			[]string{"n0", "n1", "n2"}},
+		{"count resets after a send", time.Second, []time.Duration{0, 1, 2, time.Second, time.Second + 1, 2 * time.Second},
+			[]string{"n0", "n3 (2 more since the last notification)", "n5 (1 more since the last notification)"}},
+		{"no interval", 0, []time.Duration{0, 0, 0}, []string{"n0", "n1", "n2"}},
+	}
+	for _, tt := range tests {
+		var sent []string
+		th := &notifyThrottle{interval: tt.interval, send: func(body string) error {
+			sent = append(sent, body)
+			return nil
+		}}
+		for i, d := range tt.at {
+			th.notify(start.Add(d), fmt.Sprintf("n%d", i))
+		}
+		th.flush() // as the timer would at the end of the interval
+		if !slices.Equal(sent, tt.want) {
+			t.Errorf("%s: sent %q, want %q", tt.name, sent, tt.want)
+		}
+	}
+}
+
+// TestNotifyThrottleTrailing stops notifying mid-interval and checks the
+// held-back summary is sent once the interval is up, without another call.
+func TestNotifyThrottleTrailing(t *testing.T) {
+	sent := make(chan string, 4)
+	th := &notifyThrottle{interval: 50 * time.Millisecond, send: func(body string) error {
+		sent <- body
+		return nil
+	}}
+	now := time.Now()
+	for i := range 3 {
+		th.notify(now, fmt.Sprintf("n%d", i))
+	}
+	for _, want := range []string{"n0", "n2 (1 more since the last notification)"} {
+		select {
+		case got := <-sent:
+			if got != want {
+				t.Errorf("sent %q, want %q", got, want)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("no notification, want %q", want)
+		}
+	}
+	select {
+	case got := <-sent:
+		t.Errorf("extra notification %q", got)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"net/url"
+	"os/exec"
+	"regexp"
+	"runtime"
+	"strings"
+)
+
+// urlRE matches http(s) URLs in running text. Trailing punctuation is
+// trimmed afterwards by trimURL.
+var urlRE = regexp.MustCompile(`https?://[^\s<>"'\x60]+`)
+
+// trimURL drops sentence punctuation after a URL and a closing bracket that
+// has no opening partner in the URL, as in "(see https://example.com)".
+func trimURL(u string) string {
+	for {
+		trimmed := strings.TrimRight(u, ".,;:!?")
+		for _, p := range [][2]string{{"(", ")"}, {"[", "]"}, {"{", "}"}} {
+			if strings.HasSuffix(trimmed, p[1]) && strings.Count(trimmed, p[0]) < strings.Count(trimmed, p[1]) {
+				trimmed = trimmed[:len(trimmed)-1]
+			}
+		}
+		if trimmed == u {
+			return u
+		}
+		u = trimmed
+	}
+}
+
+// findURLs returns the http(s) URLs in s, in order; only the first unless
+// all is set.
+func findURLs(s string, all bool) []string {
+	n := 1
+	if all {
+		n = -1
+	}
+	var urls []string
+	for _, m := range urlRE.FindAllString(s, n) {
+		if u := trimURL(m); u != "" {
+			urls = append(urls, u)
+		}
+	}
+	return urls
+}
+
+// singleURL returns the content as a URL if it is exactly one line holding
+// an http(s) URL.
+func singleURL(s string) (string, bool) {
+	s = strings.TrimSpace(s)
+	if s == "" || strings.ContainsAny(s, " \t\r\n") {
+		return "", false
+	}
+	u, err := url.Parse(s)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", false
+	}
+	return s, true
+}
+
+// openCommand returns the platform's "open with default application"
+// command for goos.
+func openCommand(goos, target string) (string, []string) {
+	switch goos {
+	case "darwin":
+		return "open", []string{target}
+	case "windows":
+		// The empty argument is the window title expected by start.
+		return "cmd", []string{"/c", "start", "", target}
+	default:
+		return "xdg-open", []string{target}
+	}
+}
+
+// openURL opens target with the platform opener without waiting for it.
+func openURL(target string) error {
+	bin, args := openCommand(runtime.GOOS, target)
+	cmd := exec.Command(bin, args...)
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	return cmd.Process.Release()
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestSingleURL(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+		ok   bool
+	}{
+		{"https://example.com", "https://example.com", true},
+		{"  http://example.com/path?q=1#frag\n", "http://example.com/path?q=1#frag", true},
+		{"https://example.com\nhttps://example.org", "", false},
+		{"see https://example.com", "", false},
+		{"ftp://example.com", "", false},
+		{"https://", "", false},
+		{"example.com", "", false},
+		{"mailto:someone@example.com", "", false},
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := singleURL(tt.in)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("singleURL(%q) = %q, %v; want %q, %v", tt.in, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestOpenCommand(t *testing.T) {
+	const u = "https://example.com"
+	tests := []struct {
+		goos     string
+		wantBin  string
+		wantArgs []string
+	}{
+		{"linux", "xdg-open", []string{u}},
+		{"freebsd", "xdg-open", []string{u}},
+		{"darwin", "open", []string{u}},
+		{"windows", "cmd", []string{"/c", "start", "", u}},
+	}
+	for _, tt := range tests {
+		bin, args := openCommand(tt.goos, u)
+		if bin != tt.wantBin || !slices.Equal(args, tt.wantArgs) {
+			t.Errorf("openCommand(%s) = %s %q, want %s %q", tt.goos, bin, args, tt.wantBin, tt.wantArgs)
+		}
+	}
+}
+
+func TestFindURLs(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		all  bool
+		want []string
+	}{
+		{"first of several", "docs at https://a.example/x and https://b.example/y", false, []string{"https://a.example/x"}},
+		{"all of several", "docs at https://a.example/x and\nhttp://b.example/y", true, []string{"https://a.example/x", "http://b.example/y"}},
+		{"query string and fragment", "open https://example.com/search?q=go+lang&page=2#top now", false, []string{"https://example.com/search?q=go+lang&page=2#top"}},
+		{"sentence punctuation", "See https://example.com/a.", false, []string{"https://example.com/a"}},
+		{"in parentheses", "(see https://example.com/wiki/Go_(language))", false, []string{"https://example.com/wiki/Go_(language)"}},
+		{"quoted", `href="https://example.com/q?a=1" next`, false, []string{"https://example.com/q?a=1"}},
+		{"angle brackets", "<https://example.com/>", false, []string{"https://example.com/"}},
+		{"no URL", "nothing here, not even ftp://example.com", true, nil},
+		{"empty", "", false, nil},
+	}
+	for _, tt := range tests {
+		if got := findURLs(tt.in, tt.all); !slices.Equal(got, tt.want) {
+			t.Errorf("%s: findURLs = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+// TestURLMissing runs goclip --url on output without a URL under each
+// --url-missing policy.
+func TestURLMissing(t *testing.T) {
+	tests := []struct {
+		policy  string
+		wantErr bool
+		want    string
+	}{
+		{"error", true, ""},
+		{"keep", false, "no link here\n"},
+	}
+	for _, tt := range tests {
+		out := filepath.Join(t.TempDir(), "out.txt")
+		_, _, err := runGoclip(t, "no link here\n", "-q", "--no-clip", "-f", out, "--url", "--url-missing", tt.policy)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: err = %v, want error %v", tt.policy, err, tt.wantErr)
+		}
+		got, _ := os.ReadFile(out)
+		if string(got) != tt.want {
+			t.Errorf("%s: wrote %q, want %q", tt.policy, got, tt.want)
+		}
+	}
+}
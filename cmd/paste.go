@@ -0,0 +1,263 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// detectPasteCmd returns a helper command that prints the clipboard, or the
+// primary selection when primary is set. Detection mirrors
+// detectClipboardCmd.
+func detectPasteCmd(primary bool) (string, []string, bool) {
+	wlArgs := []string{"--no-newline"}
+	xclipArgs := []string{"-selection", "clipboard", "-o"}
+	xselArgs := []string{"--clipboard", "--output"}
+	if primary {
+		wlArgs = append(wlArgs, "--primary")
+		xclipArgs = []string{"-selection", "primary", "-o"}
+		xselArgs = []string{"--primary", "--output"}
+	}
+
+	if os.Getenv("WAYLAND_DISPLAY") != "" {
+		if p, err := exec.LookPath("wl-paste"); err == nil {
+			return p, wlArgs, true
+		}
+	}
+	if os.Getenv("DISPLAY") != "" {
+		if p, err := exec.LookPath("xclip"); err == nil {
+			return p, xclipArgs, true
+		}
+		if p, err := exec.LookPath("xsel"); err == nil {
+			return p, xselArgs, true
+		}
+	}
+	if p, err := exec.LookPath("wl-paste"); err == nil {
+		return p, wlArgs, true
+	}
+	return "", nil, false
+}
+
+// readUsingCmd runs a paste helper and returns its output.
+func readUsingCmd(bin string, args []string) (string, error) {
+	cmd := exec.Command(bin, args...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("%s failed: %v (%s)", bin, err, strings.TrimSpace(stderr.String()))
+	}
+	return string(out), nil
+}
+
+// readClipboard returns the current clipboard (or primary selection)
+// contents.
+func readClipboard(primary bool) (string, error) {
+	bin, args, ok := detectPasteCmd(primary)
+	if !ok {
+		return "", fmt.Errorf("no clipboard paste helper found (install wl-clipboard or xclip/xsel)")
+	}
+	return readUsingCmd(bin, args)
+}
+
+// runSyncPrimary copies the primary selection into the clipboard.
+func runSyncPrimary(opts clipOptions, quiet, human bool) error {
+	content, err := readClipboard(true)
+	if err != nil {
+		return err
+	}
+	if content == "" {
+		return fmt.Errorf("primary selection is empty")
+	}
+	if err := writeToClipboard(content, opts); err != nil {
+		return err
+	}
+	if !quiet {
+		fmt.Fprintf(os.Stderr, "Copied %s from the primary selection to the clipboard.\n", describeSize(content, human))
+	}
+	return nil
+}
+
+// detectTargetsCmd returns a helper command that lists the formats offered
+// by the clipboard (or primary selection) owner. xsel cannot do this.
+func detectTargetsCmd(primary bool) (string, []string, bool) {
+	wlArgs := []string{"--list-types"}
+	selection := "clipboard"
+	if primary {
+		wlArgs = append(wlArgs, "--primary")
+		selection = "primary"
+	}
+	if os.Getenv("WAYLAND_DISPLAY") != "" {
+		if p, err := exec.LookPath("wl-paste"); err == nil {
+			return p, wlArgs, true
+		}
+	}
+	if os.Getenv("DISPLAY") != "" {
+		if p, err := exec.LookPath("xclip"); err == nil {
+			return p, []string{"-selection", selection, "-t", "TARGETS", "-o"}, true
+		}
+	}
+	if p, err := exec.LookPath("wl-paste"); err == nil {
+		return p, wlArgs, true
+	}
+	return "", nil, false
+}
+
+// x11MetaTargets are X11 selection targets that describe the selection
+// itself rather than a content format.
+var x11MetaTargets = map[string]bool{
+	"TARGETS":      true,
+	"TIMESTAMP":    true,
+	"MULTIPLE":     true,
+	"SAVE_TARGETS": true,
+	"DELETE":       true,
+}
+
+// parseTargets turns wl-paste --list-types or xclip TARGETS output into a
+// list of formats, one per line, without duplicates or X11 meta targets.
+func parseTargets(out string) []string {
+	var targets []string
+	seen := make(map[string]bool)
+	for _, line := range strings.Split(out, "\n") {
+		t := strings.TrimSpace(line)
+		if t == "" || x11MetaTargets[t] || seen[t] {
+			continue
+		}
+		seen[t] = true
+		targets = append(targets, t)
+	}
+	return targets
+}
+
+// listTargets returns the formats currently offered on the clipboard or
+// primary selection.
+func listTargets(primary bool) ([]string, error) {
+	bin, args, ok := detectTargetsCmd(primary)
+	if !ok {
+		return nil, fmt.Errorf("listing targets requires wl-paste or xclip")
+	}
+	out, err := readUsingCmd(bin, args)
+	if err != nil {
+		return nil, err
+	}
+	return parseTargets(out), nil
+}
+
+// preferredTargets lists, for each --prefer kind, the formats that provide
+// it in order of preference. Wayland uses MIME types, X11 also its own
+// atoms.
+var preferredTargets = map[string][]string{
+	"html": {"text/html"},
+	"text": {"text/plain;charset=utf-8", "UTF8_STRING", "text/plain", "STRING", "TEXT"},
+}
+
+// chooseTarget returns the first format wanted by prefer that is among
+// the available targets, falling back to the other kind. ok is false if
+// neither is offered.
+func chooseTarget(available []string, prefer string) (string, bool) {
+	order := []string{prefer, "text"}
+	if prefer == "text" {
+		order[1] = "html"
+	}
+	for _, kind := range order {
+		for _, t := range preferredTargets[kind] {
+			if slices.Contains(available, t) {
+				return t, true
+			}
+		}
+	}
+	return "", false
+}
+
+// readClipboardTarget reads the clipboard (or primary selection) in the
+// given format. xsel can't request formats.
+func readClipboardTarget(primary bool, target string) (string, error) {
+	bin, args, ok := detectPasteCmd(primary)
+	if !ok {
+		return "", fmt.Errorf("no clipboard paste helper found (install wl-clipboard or xclip/xsel)")
+	}
+	switch filepath.Base(bin) {
+	case "wl-paste":
+		args = append(args, "--type", target)
+	case "xclip":
+		// Insert before the trailing -o.
+		args = append(args[:len(args)-1], "-t", target, "-o")
+	default:
+		return "", fmt.Errorf("choosing a format requires wl-paste or xclip")
+	}
+	return readUsingCmd(bin, args)
+}
+
+// readPreferred reads the clipboard in the format chosen by chooseTarget,
+// or as it comes if neither html nor text formats are listed.
+func readPreferred(primary bool, prefer string) (string, error) {
+	targets, err := listTargets(primary)
+	if err != nil {
+		return "", err
+	}
+	target, ok := chooseTarget(targets, prefer)
+	if !ok {
+		return readClipboard(primary)
+	}
+	return readClipboardTarget(primary, target)
+}
+
+// runPaste implements the paste command: print the clipboard to stdout, or
+// with --list-targets the formats it is available in. --decompress expands
+// content copied with --compress.
+func runPaste(args []string) error {
+	fs := flag.NewFlagSet("paste", flag.ExitOnError)
+	list := fs.Bool("list-targets", false, "list the formats available on the clipboard")
+	primary := fs.Bool("primary", false, "read the primary selection instead of the clipboard")
+	decompress := fs.Bool("decompress", false, "expand content copied with --compress")
+	prefer := fs.String("prefer", "", "read the html or text form of the clipboard when both are offered")
+	_ = fs.Parse(args)
+	if *prefer != "" && *prefer != "html" && *prefer != "text" {
+		return fmt.Errorf("invalid --prefer %q (want html or text)", *prefer)
+	}
+
+	if *list {
+		targets, err := listTargets(*primary)
+		if err != nil {
+			return err
+		}
+		for _, t := range targets {
+			fmt.Println(t)
+		}
+		return nil
+	}
+	var content string
+	var err error
+	if *prefer != "" {
+		content, err = readPreferred(*primary, *prefer)
+	} else {
+		content, err = readClipboard(*primary)
+	}
+	if err != nil {
+		return err
+	}
+	if *decompress {
+		if content, err = decompressContent(content); err != nil {
+			return err
+		}
+	}
+	_, err = os.Stdout.WriteString(content)
+	return err
+}
+
+// appendContent joins content onto current with sep, unless current is
+// empty or already ends with sep.
+func appendContent(current, content, sep string) string {
+	if current == "" {
+		return content
+	}
+	if strings.HasSuffix(current, sep) {
+		return current + content
+	}
+	return current + sep + content
+}
@@ -0,0 +1,349 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// fakePaster installs an executable called name in dir that saves its
+// arguments to name.args, prints out and exits with status exit.
+func fakePaster(t *testing.T, dir, name, out string, exit int) {
+	t.Helper()
+	fakeHelper(t, dir, name, 0)
+	script := fmt.Sprintf("#!/bin/sh\necho \"$@\" > '%s.args'\nprintf '%%s' '%s'\nexit %d\n",
+		filepath.Join(dir, name), out, exit)
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// helperArgs returns the arguments the fake helper name was run with.
+func helperArgs(dir, name string) string {
+	b, _ := os.ReadFile(filepath.Join(dir, name+".args"))
+	return strings.TrimSpace(string(b))
+}
+
+func TestDetectPasteCmd(t *testing.T) {
+	tests := []struct {
+		name      string
+		wayland   bool
+		x11       bool
+		installed []string
+		primary   bool
+		wantBin   string
+		wantArgs  []string
+	}{
+		{"wayland clipboard", true, false, []string{"wl-paste"}, false, "wl-paste", []string{"--no-newline"}},
+		{"wayland primary", true, false, []string{"wl-paste"}, true, "wl-paste", []string{"--no-newline", "--primary"}},
+		{"xclip primary", false, true, []string{"xclip", "xsel"}, true, "xclip", []string{"-selection", "primary", "-o"}},
+		{"xsel clipboard", false, true, []string{"xsel"}, false, "xsel", []string{"--clipboard", "--output"}},
+		{"xsel primary", false, true, []string{"xsel"}, true, "xsel", []string{"--primary", "--output"}},
+		{"wayland preferred", true, true, []string{"wl-paste", "xclip"}, false, "wl-paste", []string{"--no-newline"}},
+		{"wl-paste without a display", false, false, []string{"wl-paste", "xclip"}, false, "wl-paste", []string{"--no-newline"}},
+		{"xclip without a display", false, false, []string{"xclip"}, false, "", nil},
+		{"nothing installed", true, true, nil, false, "", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := isolateClipboard(t)
+			if tt.wayland {
+				t.Setenv("WAYLAND_DISPLAY", "wayland-0")
+			}
+			if tt.x11 {
+				t.Setenv("DISPLAY", ":0")
+			}
+			for _, name := range tt.installed {
+				fakePaster(t, dir, name, "", 0)
+			}
+			bin, args, ok := detectPasteCmd(tt.primary)
+			if ok != (tt.wantBin != "") {
+				t.Fatalf("ok = %v, want %v", ok, !ok)
+			}
+			if ok && (filepath.Base(bin) != tt.wantBin || !slices.Equal(args, tt.wantArgs)) {
+				t.Errorf("got %s %v, want %s %v", filepath.Base(bin), args, tt.wantBin, tt.wantArgs)
+			}
+		})
+	}
+}
+
+// TestRunSyncPrimary copies the primary selection to the clipboard through
+// fake wl-paste and wl-copy helpers.
+func TestRunSyncPrimary(t *testing.T) {
+	tests := []struct {
+		name      string
+		primary   string
+		pasteExit int // -1 means wl-paste is not installed
+		wantErr   string
+	}{
+		{"copies the selection", "selected text\n", 0, ""},
+		{"keeps multibyte text", "héllo 🙂", 0, ""},
+		{"empty selection", "", 0, "primary selection is empty"},
+		{"paste helper fails", "x", 1, "wl-paste failed"},
+		{"no paste helper", "", -1, "no clipboard paste helper"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := isolateClipboard(t)
+			t.Setenv("WAYLAND_DISPLAY", "wayland-0")
+			if tt.pasteExit >= 0 {
+				fakePaster(t, dir, "wl-paste", tt.primary, tt.pasteExit)
+			}
+			fakeHelper(t, dir, "wl-copy", 0)
+			err := runSyncPrimary(clipOptions{noFallback: true}, true, false)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				if got := helperInput(dir, "wl-copy"); got != "" {
+					t.Errorf("wl-copy ran with %q after an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := helperArgs(dir, "wl-paste"); !strings.Contains(got, "--primary") {
+				t.Errorf("wl-paste args %q, want --primary", got)
+			}
+			if got := helperInput(dir, "wl-copy"); got != tt.primary {
+				t.Errorf("clipboard got %q, want %q", got, tt.primary)
+			}
+			if got := helperArgs(dir, "wl-copy"); strings.Contains(got, "--primary") {
+				t.Errorf("wl-copy args %q write the primary selection", got)
+			}
+		})
+	}
+}
+
+func TestParseTargets(t *testing.T) {
+	tests := []struct {
+		name string
+		out  string
+		want []string
+	}{
+		{"wl-paste", "text/html\ntext/plain;charset=utf-8\nimage/png\n", []string{"text/html", "text/plain;charset=utf-8", "image/png"}},
+		{"xclip", "TIMESTAMP\nTARGETS\nMULTIPLE\nSAVE_TARGETS\nUTF8_STRING\nSTRING\ntext/html\n", []string{"UTF8_STRING", "STRING", "text/html"}},
+		{"duplicates and padding", "  text/plain \r\ntext/plain\n\n\nimage/png", []string{"text/plain", "image/png"}},
+		{"empty", "", nil},
+		{"only meta targets", "TARGETS\nTIMESTAMP\n", nil},
+	}
+	for _, tt := range tests {
+		if got := parseTargets(tt.out); !slices.Equal(got, tt.want) {
+			t.Errorf("%s: parseTargets = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestListTargets(t *testing.T) {
+	tests := []struct {
+		name     string
+		helper   string
+		x11      bool
+		primary  bool
+		wantArgs string
+	}{
+		{"wl-paste", "wl-paste", false, false, "--list-types"},
+		{"wl-paste primary", "wl-paste", false, true, "--list-types --primary"},
+		{"xclip", "xclip", true, false, "-selection clipboard -t TARGETS -o"},
+		{"xclip primary", "xclip", true, true, "-selection primary -t TARGETS -o"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := isolateClipboard(t)
+			if tt.x11 {
+				t.Setenv("DISPLAY", ":0")
+			}
+			fakePaster(t, dir, tt.helper, "TARGETS\nUTF8_STRING\nimage/png\n", 0)
+			got, err := listTargets(tt.primary)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := []string{"UTF8_STRING", "image/png"}; !slices.Equal(got, want) {
+				t.Errorf("targets %q, want %q", got, want)
+			}
+			if got := helperArgs(dir, tt.helper); got != tt.wantArgs {
+				t.Errorf("args %q, want %q", got, tt.wantArgs)
+			}
+		})
+	}
+
+	isolateClipboard(t)
+	if _, err := listTargets(false); err == nil {
+		t.Error("no helper: want an error")
+	}
+}
+
+func TestAppendContent(t *testing.T) {
+	tests := []struct {
+		current, content, sep, want string
+	}{
+		{"old", "new", "\n", "old\nnew"},
+		{"old\n", "new", "\n", "old\nnew"},
+		{"", "new", "\n", "new"},
+		{"a", "b", ", ", "a, b"},
+		{"a, ", "b", ", ", "a, b"},
+		{"a", "b", "", "ab"},
+		{"old\n", "", "\n", "old\n"},
+	}
+	for _, tt := range tests {
+		if got := appendContent(tt.current, tt.content, tt.sep); got != tt.want {
+			t.Errorf("appendContent(%q, %q, %q) = %q, want %q", tt.current, tt.content, tt.sep, got, tt.want)
+		}
+	}
+}
+
+// TestAppendClipboard runs goclip --append-clipboard against a fake
+// current clipboard.
+func TestAppendClipboard(t *testing.T) {
+	tests := []struct {
+		name    string
+		current string
+		paster  bool
+		args    []string
+		want    string
+	}{
+		{"appended", "old", true, nil, "old\nnew\n"},
+		{"custom separator", "old", true, []string{"--append-separator", `\t`}, "old\tnew\n"},
+		{"empty clipboard", "", true, nil, "new\n"},
+		{"no paste helper", "", false, nil, "new\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := isolateClipboard(t)
+			t.Setenv("WAYLAND_DISPLAY", "wayland-0")
+			if tt.paster {
+				fakePaster(t, dir, "wl-paste", tt.current, 0)
+			}
+			fakeHelper(t, dir, "wl-copy", 0)
+			args := append([]string{"-q", "--no-fallback", "--append-clipboard"}, tt.args...)
+			if _, stderr, err := runGoclip(t, "new\n", args...); err != nil {
+				t.Fatalf("goclip: %v\n%s", err, stderr)
+			}
+			if got := helperInput(dir, "wl-copy"); got != tt.want {
+				t.Errorf("clipboard %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSkipIdentical(t *testing.T) {
+	tests := []struct {
+		name      string
+		current   string
+		pasteExit int
+		wantWrite bool
+	}{
+		{"identical", "same\n", 0, false},
+		{"different", "other\n", 0, true},
+		{"differs only in the trailing newline", "same", 0, true},
+		{"paste fails", "same\n", 1, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := isolateClipboard(t)
+			t.Setenv("WAYLAND_DISPLAY", "wayland-0")
+			fakePaster(t, dir, "wl-paste", tt.current, tt.pasteExit)
+			fakeHelper(t, dir, "wl-copy", 0)
+			_, stderr, err := runGoclip(t, "same\n", "--no-fallback", "--skip-identical")
+			if err != nil {
+				t.Fatalf("goclip: %v\n%s", err, stderr)
+			}
+			_, statErr := os.Stat(filepath.Join(dir, "wl-copy.args"))
+			if wrote := statErr == nil; wrote != tt.wantWrite {
+				t.Errorf("wl-copy ran = %v, want %v", wrote, tt.wantWrite)
+			}
+			if got := strings.Contains(stderr, "Already on the clipboard."); got == tt.wantWrite {
+				t.Errorf("stderr %q", stderr)
+			}
+
+			stdout, stderr, err := runGoclip(t, "same\n", "--no-fallback", "--skip-identical", "--json")
+			if err != nil {
+				t.Fatalf("goclip --json: %v\n%s", err, stderr)
+			}
+			var rep report
+			if err := json.Unmarshal([]byte(stdout), &rep); err != nil {
+				t.Fatalf("--json output %q: %v", stdout, err)
+			}
+			if !rep.Copied || rep.Unchanged == tt.wantWrite {
+				t.Errorf("copied = %v, unchanged = %v", rep.Copied, rep.Unchanged)
+			}
+		})
+	}
+}
+
+func TestChooseTarget(t *testing.T) {
+	tests := []struct {
+		name      string
+		available []string
+		prefer    string
+		want      string
+		wantOK    bool
+	}{
+		{"html offered", []string{"UTF8_STRING", "text/html"}, "html", "text/html", true},
+		{"text offered", []string{"text/html", "UTF8_STRING"}, "text", "UTF8_STRING", true},
+		{"best text form", []string{"STRING", "text/plain", "text/plain;charset=utf-8"}, "text", "text/plain;charset=utf-8", true},
+		{"html falls back to text", []string{"UTF8_STRING", "image/png"}, "html", "UTF8_STRING", true},
+		{"text falls back to html", []string{"text/html"}, "text", "text/html", true},
+		{"neither", []string{"image/png"}, "html", "", false},
+		{"nothing", nil, "text", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := chooseTarget(tt.available, tt.prefer)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("%s: chooseTarget = %q, %v, want %q, %v", tt.name, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+// fakeTargetsHelper installs a paste helper called name in dir that lists
+// targets when asked for them and otherwise prints the arguments it was
+// given, so a test can see which format was requested.
+func fakeTargetsHelper(t *testing.T, dir, name, targets string) {
+	t.Helper()
+	fakeHelper(t, dir, name, 0)
+	script := fmt.Sprintf("#!/bin/sh\ncase \"$*\" in\n*TARGETS*|*--list-types*) printf '%%s' '%s' ;;\n*) printf 'read %%s' \"$*\" ;;\nesac\n", targets)
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReadPreferred(t *testing.T) {
+	both := "TARGETS\ntext/html\nUTF8_STRING\n"
+	tests := []struct {
+		name    string
+		helper  string
+		targets string
+		prefer  string
+		want    string
+	}{
+		{"xclip html", "xclip", both, "html", "read -selection clipboard -t text/html -o"},
+		{"xclip text", "xclip", both, "text", "read -selection clipboard -t UTF8_STRING -o"},
+		{"xclip no html", "xclip", "TARGETS\nUTF8_STRING\n", "html", "read -selection clipboard -t UTF8_STRING -o"},
+		{"xclip neither", "xclip", "TARGETS\nimage/png\n", "html", "read -selection clipboard -o"},
+		{"wl-paste html", "wl-paste", "text/html\ntext/plain\n", "html", "read --no-newline --type text/html"},
+		{"wl-paste text", "wl-paste", "text/html\ntext/plain\n", "text", "read --no-newline --type text/plain"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := isolateClipboard(t)
+			if tt.helper == "xclip" {
+				t.Setenv("DISPLAY", ":0")
+			} else {
+				t.Setenv("WAYLAND_DISPLAY", "wayland-0")
+			}
+			fakeTargetsHelper(t, dir, tt.helper, tt.targets)
+			got, err := readPreferred(false, tt.prefer)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// defaultPastebinURL accepts the raw content as the request body and
+// answers with the paste URL.
+const defaultPastebinURL = "https://paste.rs/"
+
+// pastebinSettings returns the paste endpoint and HTTP method, overridable
+// with GOCLIP_PASTEBIN_URL and GOCLIP_PASTEBIN_METHOD.
+func pastebinSettings() (string, string) {
+	endpoint := os.Getenv("GOCLIP_PASTEBIN_URL")
+	if endpoint == "" {
+		endpoint = defaultPastebinURL
+	}
+	method := strings.ToUpper(os.Getenv("GOCLIP_PASTEBIN_METHOD"))
+	if method == "" {
+		method = http.MethodPost
+	}
+	return endpoint, method
+}
+
+// uploadPaste sends content as the request body and returns the paste URL
+// from the response body.
+func uploadPaste(endpoint, method, content string) (string, error) {
+	req, err := http.NewRequest(method, endpoint, strings.NewReader(content))
+	if err != nil {
+		return "", fmt.Errorf("build request: %w", err)
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if err != nil {
+		return "", fmt.Errorf("read response: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return "", fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	link := strings.TrimSpace(string(body))
+	if u, err := url.Parse(link); err != nil || u.Scheme == "" || u.Host == "" {
+		return "", fmt.Errorf("unexpected response %q", link)
+	}
+	return link, nil
+}
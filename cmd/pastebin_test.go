@@ -0,0 +1,105 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestPastebinSettings(t *testing.T) {
+	tests := []struct {
+		url, method         string
+		wantURL, wantMethod string
+	}{
+		{"", "", defaultPastebinURL, http.MethodPost},
+		{"https://paste.example/api", "put", "https://paste.example/api", http.MethodPut},
+	}
+	for _, tt := range tests {
+		t.Setenv("GOCLIP_PASTEBIN_URL", tt.url)
+		t.Setenv("GOCLIP_PASTEBIN_METHOD", tt.method)
+		if u, m := pastebinSettings(); u != tt.wantURL || m != tt.wantMethod {
+			t.Errorf("pastebinSettings() = %s %s, want %s %s", m, u, tt.wantMethod, tt.wantURL)
+		}
+	}
+}
+
+// pasteServer is a paste service that records the request and answers
+// with status and reply.
+func pasteServer(t *testing.T, status int, reply string) (*httptest.Server, *http.Request, *string) {
+	t.Helper()
+	var got http.Request
+	var body string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		got, body = *r, string(b)
+		w.WriteHeader(status)
+		io.WriteString(w, reply)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &got, &body
+}
+
+func TestUploadPaste(t *testing.T) {
+	tests := []struct {
+		name    string
+		method  string
+		status  int
+		reply   string
+		want    string
+		wantErr string
+	}{
+		{"posted", http.MethodPost, 201, "https://paste.example/abc\n", "https://paste.example/abc", ""},
+		{"put", http.MethodPut, 200, "https://paste.example/def", "https://paste.example/def", ""},
+		{"server error", http.MethodPost, 503, "try later\n", "", "503 Service Unavailable: try later"},
+		{"not a URL", http.MethodPost, 200, "ok", "", `unexpected response "ok"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, req, body := pasteServer(t, tt.status, tt.reply)
+			got, err := uploadPaste(srv.URL+"/new", tt.method, "line 1\nhé\n")
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("err = %v, want %q", err, tt.wantErr)
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			} else if got != tt.want {
+				t.Errorf("URL %q, want %q", got, tt.want)
+			}
+			if req.Method != tt.method || req.URL.Path != "/new" {
+				t.Errorf("request %s %s", req.Method, req.URL.Path)
+			}
+			if *body != "line 1\nhé\n" {
+				t.Errorf("body %q", *body)
+			}
+			if ct := req.Header.Get("Content-Type"); ct != "text/plain; charset=utf-8" {
+				t.Errorf("Content-Type %q", ct)
+			}
+		})
+	}
+}
+
+// TestPastebinFallback runs goclip with a clipboard helper that fails and
+// checks the content is uploaded and the URL printed instead.
+func TestPastebinFallback(t *testing.T) {
+	dir := isolateClipboard(t)
+	fakeHelper(t, dir, "xclip", 1)
+	srv, req, body := pasteServer(t, 200, "https://paste.example/xyz\n")
+	t.Setenv("GOCLIP_PASTEBIN_URL", srv.URL)
+
+	out, stderr, err := runGoclip(t, "headless output\n", "-q", "--clipboard-cmd", "xclip", "--no-fallback", "--pastebin")
+	if err != nil {
+		t.Fatalf("goclip: %v\n%s", err, stderr)
+	}
+	if req.Method != http.MethodPost || *body != "headless output\n" {
+		t.Errorf("uploaded %s %q", req.Method, *body)
+	}
+	if got := helperInput(dir, "xclip"); got != "headless output\n" {
+		t.Errorf("xclip wasn't tried first: got %q", got)
+	}
+	if first, _, _ := strings.Cut(out, "\n"); first != "https://paste.example/xyz" {
+		t.Errorf("printed %q, want the paste URL", out)
+	}
+}
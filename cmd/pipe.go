@@ -0,0 +1,43 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+)
+
+// pipeContent runs command through sh with content on stdin and returns
+// what it prints. Its stderr goes to goclip's stderr.
+func pipeContent(command, content string) (string, error) {
+	cmd := exec.Command("sh", "-c", command)
+	cmd.Stdin = strings.NewReader(content)
+	cmd.Stderr = os.Stderr
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("%s: %w", command, err)
+	}
+	return out.String(), nil
+}
+
+// pipeExitCode maps a failed --pipe command to goclip's exit status for
+// --pipe-preserve-exit: the command's own status if it exited, 128 plus the
+// signal number if it was killed, as shells report it, and 1 if it could
+// not be run at all.
+func pipeExitCode(err error) int {
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		return 1
+	}
+	if ws, ok := exitErr.Sys().(syscall.WaitStatus); ok && ws.Signaled() {
+		return 128 + int(ws.Signal())
+	}
+	if code := exitErr.ExitCode(); code > 0 {
+		return code
+	}
+	return 1
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"errors"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestPipeContent(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("--pipe runs sh")
+	}
+	got, err := pipeContent("tr a-z A-Z", "hello\n")
+	if err != nil || got != "HELLO\n" {
+		t.Errorf("pipeContent = %q, %v, want %q", got, err, "HELLO\n")
+	}
+	if _, err := pipeContent("cat >/dev/null; exit 3", "x"); err == nil {
+		t.Error("failing command: want an error")
+	}
+}
+
+func TestPipeExitCode(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("--pipe runs sh")
+	}
+	tests := []struct {
+		command string
+		want    int
+	}{
+		{"exit 42", 42},
+		{"exit 1", 1},
+		{"exit 255", 255},
+		{"kill -TERM $$", 128 + 15},
+		{"kill -KILL $$", 128 + 9},
+	}
+	for _, tt := range tests {
+		_, err := pipeContent(tt.command, "")
+		if err == nil {
+			t.Errorf("%q: no error", tt.command)
+			continue
+		}
+		if got := pipeExitCode(err); got != tt.want {
+			t.Errorf("%q: exit code %d, want %d", tt.command, got, tt.want)
+		}
+	}
+	if got := pipeExitCode(errors.New("sh: not found")); got != 1 {
+		t.Errorf("command not run: exit code %d, want 1", got)
+	}
+}
+
+// TestPipePreserveExit checks goclip's own exit status when the --pipe
+// command fails, with and without --pipe-preserve-exit.
+func TestPipePreserveExit(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("--pipe runs sh")
+	}
+	tests := []struct {
+		args []string
+		want int
+	}{
+		{[]string{"--pipe", "exit 42"}, 1},
+		{[]string{"--pipe", "exit 42", "--pipe-preserve-exit"}, 42},
+		{[]string{"--pipe", "kill -TERM $$", "--pipe-preserve-exit"}, 128 + 15},
+	}
+	for _, tt := range tests {
+		out := filepath.Join(t.TempDir(), "out.txt")
+		args := append([]string{"-q", "--no-clip", "-f", out}, tt.args...)
+		_, stderr, err := runGoclip(t, "data\n", args...)
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) {
+			t.Fatalf("%v: err = %v, want an exit status\n%s", tt.args, err, stderr)
+		}
+		if got := exitErr.ExitCode(); got != tt.want {
+			t.Errorf("%v: exit status %d, want %d", tt.args, got, tt.want)
+		}
+	}
+}
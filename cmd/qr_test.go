@@ -0,0 +1,259 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestEncodeQRCapacity(t *testing.T) {
+	tests := []struct {
+		n        int
+		wantSize int // 0 means an error is expected
+	}{
+		{0, 21},
+		{17, 21}, // version 1-L holds 17 bytes
+		{18, 25},
+		{32, 25},
+		{33, 29},
+		{134, 41}, // version 6
+		{135, 45}, // version 7, the first with version information
+		{271, 57}, // version 10, the limit
+		{272, 0},
+		{5000, 0},
+	}
+	for _, tt := range tests {
+		qr, err := encodeQR(bytes.Repeat([]byte("a"), tt.n))
+		if tt.wantSize == 0 {
+			if err == nil || !strings.Contains(err.Error(), "too large") {
+				t.Errorf("%d bytes: err = %v, want a too-large error", tt.n, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%d bytes: %v", tt.n, err)
+			continue
+		}
+		if qr.size != tt.wantSize {
+			t.Errorf("%d bytes: size %d, want %d", tt.n, qr.size, tt.wantSize)
+		}
+	}
+}
+
+// TestRSRemainder checks the Reed–Solomon encoder against the worked
+// "HELLO WORLD" 1-M example from the QR specification tutorials.
+func TestRSRemainder(t *testing.T) {
+	data := []byte{32, 91, 11, 120, 209, 114, 220, 77, 67, 64, 236, 17, 236, 17, 236, 17}
+	want := []byte{196, 35, 39, 119, 235, 215, 231, 226, 93, 23}
+	if got := rsRemainder(data, rsDivisor(10)); !bytes.Equal(got, want) {
+		t.Errorf("rsRemainder = %v, want %v", got, want)
+	}
+}
+
+// TestQRFormatBits compares the format information in the top-left corner
+// with the published table for level L.
+func TestQRFormatBits(t *testing.T) {
+	want := []string{
+		"111011111000100", "111001011110011", "111110110101010", "111100010011101",
+		"110011000101111", "110001100011000", "110110001000001", "110100101110110",
+	}
+	for mask, w := range want {
+		qr := newQRCode(1)
+		qr.drawFormatBits(mask)
+		if got := readFormatBits(qr); got != w {
+			t.Errorf("mask %d: format bits %s, want %s", mask, got, w)
+		}
+	}
+}
+
+// TestQRVersionBits checks the version 7 information block against the
+// published value.
+func TestQRVersionBits(t *testing.T) {
+	qr := newQRCode(7)
+	qr.drawFunctionPatterns(7)
+	var got strings.Builder
+	for i := 17; i >= 0; i-- {
+		got.WriteString(moduleBit(qr.modules[i/3][qr.size-11+i%3]))
+	}
+	if want := "000111110010010100"; got.String() != want {
+		t.Errorf("version bits %s, want %s", got.String(), want)
+	}
+}
+
+// TestQRRoundTrip decodes generated symbols and checks the data comes
+// back, for sizes exercising single and multiple blocks.
+func TestQRRoundTrip(t *testing.T) {
+	for _, s := range []string{
+		"",
+		"https://example.com/",
+		"a token: 3f9a-11c2-8e7d",
+		strings.Repeat("0123456789", 10),
+		strings.Repeat("xyz", 90),
+		"héllo wörld 🙂",
+	} {
+		qr, err := encodeQR([]byte(s))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := decodeQR(t, qr); got != s {
+			t.Errorf("decoded %q, want %q", got, s)
+		}
+	}
+}
+
+func TestRenderQR(t *testing.T) {
+	qr, err := encodeQR([]byte("hi"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out strings.Builder
+	if err := renderQR(&out, qr); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	if want := (qr.size + 8 + 1) / 2; len(lines) != want {
+		t.Errorf("%d lines, want %d", len(lines), want)
+	}
+	for _, line := range lines {
+		body := strings.TrimSuffix(strings.TrimPrefix(line, "\x1b[97;40m"), "\x1b[0m")
+		if n := len([]rune(body)); n != qr.size+8 {
+			t.Errorf("line is %d columns, want %d", n, qr.size+8)
+		}
+	}
+}
+
+func moduleBit(dark bool) string {
+	if dark {
+		return "1"
+	}
+	return "0"
+}
+
+// readFormatBits reads the top-left copy of the format information, most
+// significant bit first.
+func readFormatBits(qr *qrCode) string {
+	var b strings.Builder
+	for i := 14; i >= 0; i-- {
+		var x, y int
+		switch {
+		case i < 6:
+			x, y = 8, i
+		case i < 8:
+			x, y = 8, i+1
+		case i == 8:
+			x, y = 7, 8
+		default:
+			x, y = 14-i, 8
+		}
+		b.WriteString(moduleBit(qr.modules[y][x]))
+	}
+	return b.String()
+}
+
+// decodeQR reads qr back: it finds the mask from the format bits, reads the
+// codewords in zigzag order, de-interleaves and checks each block's error
+// correction, and parses the byte-mode segment.
+func decodeQR(t *testing.T, qr *qrCode) string {
+	t.Helper()
+	version := (qr.size - 17) / 4
+	format := readFormatBits(qr)
+	var mask int
+	for m := range 8 {
+		ref := newQRCode(version)
+		ref.drawFormatBits(m)
+		if readFormatBits(ref) == format {
+			mask = m
+			break
+		}
+		if m == 7 {
+			t.Fatalf("format bits %s match no level L mask", format)
+		}
+	}
+
+	plain := newQRCode(version)
+	plain.drawFunctionPatterns(version)
+	for y := range qr.size {
+		copy(plain.modules[y], qr.modules[y])
+	}
+	plain.applyMask(mask)
+
+	var bits []bool
+	for right := qr.size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5
+		}
+		for vert := range qr.size {
+			for j := range 2 {
+				x, y := right-j, vert
+				if (right+1)&2 == 0 {
+					y = qr.size - 1 - vert
+				}
+				if !plain.isFunction[y][x] {
+					bits = append(bits, plain.modules[y][x])
+				}
+			}
+		}
+	}
+	codewords := make([]byte, len(bits)/8)
+	for i := range codewords {
+		for j := range 8 {
+			if bits[8*i+j] {
+				codewords[i] |= 1 << (7 - j)
+			}
+		}
+	}
+
+	b := qrBlocks[version]
+	nblocks := b.n1 + b.n2
+	blocks := make([][]byte, nblocks)
+	pos := 0
+	for i := range max(b.d1, b.d2) {
+		for k := range nblocks {
+			size := b.d1
+			if k >= b.n1 {
+				size = b.d2
+			}
+			if i < size {
+				blocks[k] = append(blocks[k], codewords[pos])
+				pos++
+			}
+		}
+	}
+	var data []byte
+	for k := range nblocks {
+		ec := make([]byte, b.ec)
+		for i := range b.ec {
+			ec[i] = codewords[pos+i*nblocks+k]
+		}
+		if want := rsRemainder(blocks[k], rsDivisor(b.ec)); !bytes.Equal(ec, want) {
+			t.Fatalf("block %d: error correction %v, want %v", k, ec, want)
+		}
+		data = append(data, blocks[k]...)
+	}
+
+	read := func(n int) int {
+		v := 0
+		for range n {
+			v = v<<1 | int(data[0]>>7)
+			carry := byte(0)
+			for i := len(data) - 1; i >= 0; i-- {
+				next := data[i] >> 7
+				data[i] = data[i]<<1 | carry
+				carry = next
+			}
+		}
+		return v
+	}
+	if mode := read(4); mode != 0b0100 {
+		t.Fatalf("mode %04b, want byte mode", mode)
+	}
+	n := read(qrCountBits(version))
+	out := make([]byte, n)
+	for i := range out {
+		out[i] = byte(read(8))
+	}
+	if term := read(4); term != 0 {
+		t.Errorf("terminator %04b, want 0000", term)
+	}
+	return string(out)
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// span is a half-open range [start, end) parsed from "START:END". Either
+// side may be omitted; an open end is stored as -1.
+type span struct {
+	start, end int
+}
+
+// parseSpan parses a "START:END" range such as "100:", ":50" or "10:20".
+func parseSpan(spec string) (span, error) {
+	lo, hi, ok := strings.Cut(spec, ":")
+	if !ok {
+		return span{}, fmt.Errorf("range %q must be START:END", spec)
+	}
+	sp := span{end: -1}
+	var err error
+	if lo != "" {
+		if sp.start, err = strconv.Atoi(lo); err != nil || sp.start < 0 {
+			return span{}, fmt.Errorf("range %q: invalid start", spec)
+		}
+	}
+	if hi != "" {
+		if sp.end, err = strconv.Atoi(hi); err != nil || sp.end < 0 {
+			return span{}, fmt.Errorf("range %q: invalid end", spec)
+		}
+		if sp.end < sp.start {
+			return span{}, fmt.Errorf("range %q: end is before start", spec)
+		}
+	}
+	return sp, nil
+}
+
+// parseLineSpan parses a --lines range, which is 1-based and inclusive on
+// the command line; 0 also means line 1.
+func parseLineSpan(spec string) (span, error) {
+	sp, err := parseSpan(spec)
+	if err != nil {
+		return span{}, err
+	}
+	sp.start = max(sp.start-1, 0)
+	return sp, nil
+}
+
+// clamp returns the span's bounds limited to a sequence of length n, so an
+// out-of-range span selects whatever part of it exists.
+func (sp span) clamp(n int) (int, int) {
+	end := n
+	if sp.end >= 0 {
+		end = min(sp.end, n)
+	}
+	return min(sp.start, end), end
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseSpan(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    span
+		wantErr string
+	}{
+		{"10:20", span{10, 20}, ""},
+		{"100:", span{100, -1}, ""},
+		{":50", span{0, 50}, ""},
+		{":", span{0, -1}, ""},
+		{"5:5", span{5, 5}, ""},
+		{"0:0", span{0, 0}, ""},
+		{"20", span{}, "must be START:END"},
+		{"", span{}, "must be START:END"},
+		{"a:5", span{}, "invalid start"},
+		{"-1:5", span{}, "invalid start"},
+		{"1:x", span{}, "invalid end"},
+		{"1:-5", span{}, "invalid end"},
+		{"20:10", span{}, "end is before start"},
+		{"1:2:3", span{}, "invalid end"},
+	}
+	for _, tt := range tests {
+		got, err := parseSpan(tt.spec)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("parseSpan(%q) err = %v, want %q", tt.spec, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("parseSpan(%q) = %v, %v, want %v", tt.spec, got, err, tt.want)
+		}
+	}
+}
+
+// TestByteRange selects bytes as --bytes does, from parsing to clamping.
+func TestByteRange(t *testing.T) {
+	const in = "0123456789"
+	tests := []struct{ spec, want string }{
+		{"2:5", "234"},
+		{"7:", "789"},
+		{":3", "012"},
+		{":", in},
+		{"4:4", ""},
+		{"8:100", "89"}, // end past the input
+		{"50:", ""},     // start past the input
+		{"50:60", ""},   // both past the input
+		{":100", in},    // the whole input
+		{"10:10", ""},   // at the very end
+	}
+	for _, tt := range tests {
+		sp, err := parseSpan(tt.spec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		lo, hi := sp.clamp(len(in))
+		if got := in[lo:hi]; got != tt.want {
+			t.Errorf("--bytes %s = %q, want %q", tt.spec, got, tt.want)
+		}
+	}
+}
+
+// TestLineRange selects lines as --lines does: 1-based and inclusive.
+func TestLineRange(t *testing.T) {
+	const in = "one\ntwo\nthree\nfour\nfive\n"
+	tests := []struct{ spec, want string }{
+		{"2:4", "two\nthree\nfour\n"},
+		{"1:1", "one\n"},
+		{"0:2", "one\ntwo\n"}, // 0 also means line 1
+		{"4:", "four\nfive\n"},
+		{":2", "one\ntwo\n"},
+		{":", in},
+		{"5:5", "five\n"},
+		{"4:100", "four\nfive\n"}, // end beyond the line count
+		{"6:", ""},                // start beyond the line count
+		{"10:20", ""},
+	}
+	for _, tt := range tests {
+		sp, err := parseLineSpan(tt.spec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := lineSpan(in, sp); got != tt.want {
+			t.Errorf("--lines %s = %q, want %q", tt.spec, got, tt.want)
+		}
+	}
+
+	// The last line keeps its missing newline only when it is selected.
+	sp, _ := parseLineSpan("1:2")
+	if got := lineSpan("a\nb\nc", sp); got != "a\nb\n" {
+		t.Errorf("first lines of unterminated input = %q", got)
+	}
+	sp, _ = parseLineSpan("2:")
+	if got := lineSpan("a\nb\nc", sp); got != "b\nc" {
+		t.Errorf("last lines of unterminated input = %q", got)
+	}
+	if _, err := parseLineSpan("3:1"); err == nil {
+		t.Error("parseLineSpan(3:1) succeeded")
+	}
+}
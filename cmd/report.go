@@ -0,0 +1,86 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// report is the machine-readable run summary printed by --json.
+type report struct {
+	Label       string `json:"label,omitempty"`
+	Bytes       int    `json:"bytes"`
+	Lines       int    `json:"lines"`
+	Copied      bool   `json:"copied"`
+	Unchanged   bool   `json:"unchanged,omitempty"` // --skip-identical found it already copied
+	File        string `json:"file,omitempty"`
+	URL         string `json:"url,omitempty"`
+	Matched     *int   `json:"matched,omitempty"`
+	Dropped     *int   `json:"dropped,omitempty"`
+	Redacted    bool   `json:"redacted,omitempty"`    // --secret withheld Content
+	Sampled     bool   `json:"sampled,omitempty"`     // --sample cut the input short
+	Occurrences *int   `json:"occurrences,omitempty"` // --count-of or --count-of-regex
+	Content     string `json:"content"`
+}
+
+// countLines counts the lines in s, including an unterminated last line.
+func countLines(s string) int {
+	n := strings.Count(s, "\n")
+	if s != "" && !strings.HasSuffix(s, "\n") {
+		n++
+	}
+	return n
+}
+
+// countMetrics are the measures --copy-count can copy.
+var countMetrics = []string{"lines", "words", "bytes", "chars"}
+
+// countContent measures s by metric, one of countMetrics.
+func countContent(s, metric string) int {
+	switch metric {
+	case "words":
+		return len(strings.Fields(s))
+	case "bytes":
+		return len(s)
+	case "chars":
+		return utf8.RuneCountInString(s)
+	default:
+		return countLines(s)
+	}
+}
+
+// newReport fills in the size fields of a report for content.
+func newReport(content string) report {
+	return report{Bytes: len(content), Lines: countLines(content), Content: content}
+}
+
+// writeReport encodes r as a single line of JSON. Content is not
+// HTML-escaped so it reads the same as what was copied.
+func writeReport(w io.Writer, r report) error {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	return enc.Encode(r)
+}
+
+// previewWidth is the column limit for each --preview line.
+const previewWidth = 100
+
+// writePreview prints the first n lines of content to w, shortening lines
+// wider than previewWidth.
+func writePreview(w io.Writer, content string, n int) {
+	lines, _ := splitLines(content)
+	if n < len(lines) {
+		fmt.Fprintf(w, "Preview (first %d of %d lines):\n", n, len(lines))
+		lines = lines[:n]
+	} else {
+		fmt.Fprintf(w, "Preview (%d lines):\n", len(lines))
+	}
+	for _, line := range lines {
+		if displayWidth(line) > previewWidth {
+			line = headWidth(line, previewWidth-1) + "…"
+		}
+		fmt.Fprintf(w, "  %s\n", line)
+	}
+}
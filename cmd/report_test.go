@@ -0,0 +1,115 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestReportCounts(t *testing.T) {
+	matched, dropped := 3, 0
+	tests := []struct {
+		name string
+		r    report
+		want map[string]any
+	}{
+		{"no grep", newReport("a\nb"), map[string]any{"bytes": 3.0, "lines": 2.0, "content": "a\nb"}},
+		{"matched", report{Matched: &matched}, map[string]any{"matched": 3.0}},
+		{"zero dropped is still reported", report{Dropped: &dropped}, map[string]any{"dropped": 0.0}},
+		{"label", report{Label: "build-output"}, map[string]any{"label": "build-output"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := writeReport(&buf, tt.r); err != nil {
+				t.Fatal(err)
+			}
+			var got map[string]any
+			if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+				t.Fatalf("invalid JSON %q: %v", buf.String(), err)
+			}
+			for k, v := range tt.want {
+				if got[k] != v {
+					t.Errorf("%s = %v, want %v (in %s)", k, got[k], v, buf.String())
+				}
+			}
+			for _, k := range []string{"matched", "dropped"} {
+				if _, ok := tt.want[k]; !ok {
+					if _, ok := got[k]; ok {
+						t.Errorf("%s present without grep", k)
+					}
+				}
+			}
+			if _, ok := got["label"]; ok && tt.r.Label == "" {
+				t.Errorf("label present without --label")
+			}
+		})
+	}
+}
+
+func TestCountLines(t *testing.T) {
+	tests := []struct {
+		in   string
+		want int
+	}{
+		{"", 0},
+		{"a", 1},
+		{"a\n", 1},
+		{"a\nb", 2},
+		{"\n\n", 2},
+	}
+	for _, tt := range tests {
+		if got := countLines(tt.in); got != tt.want {
+			t.Errorf("countLines(%q) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestCountContent(t *testing.T) {
+	const text = "héllo wörld\n  two\twords\n\nlast"
+	tests := []struct {
+		in, metric string
+		want       int
+	}{
+		{text, "lines", 4},
+		{text, "words", 5},
+		{text, "bytes", 31},
+		{text, "chars", 29},
+		{"", "lines", 0},
+		{"", "words", 0},
+		{"   \n", "words", 0},
+		{"🙂", "bytes", 4},
+		{"🙂", "chars", 1},
+	}
+	for _, tt := range tests {
+		if got := countContent(tt.in, tt.metric); got != tt.want {
+			t.Errorf("countContent(%q, %s) = %d, want %d", tt.in, tt.metric, got, tt.want)
+		}
+	}
+}
+
+func TestWritePreview(t *testing.T) {
+	long := strings.Repeat("x", previewWidth+20)
+	wide := strings.Repeat("界", previewWidth)
+	tests := []struct {
+		name    string
+		content string
+		n       int
+		want    string
+	}{
+		{"fewer lines than n", "a\nb\n", 5, "Preview (2 lines):\n  a\n  b\n"},
+		{"exactly n lines", "a\nb\nc", 3, "Preview (3 lines):\n  a\n  b\n  c\n"},
+		{"more lines than n", "a\nb\nc\nd\n", 2, "Preview (first 2 of 4 lines):\n  a\n  b\n"},
+		{"long line shortened", long + "\nshort\n", 1, "Preview (first 1 of 2 lines):\n  " + long[:previewWidth-1] + "…\n"},
+		{"wide characters counted by column", wide, 1, "Preview (1 lines):\n  " + strings.Repeat("界", (previewWidth-1)/2) + "…\n"},
+		{"line at the width kept", long[:previewWidth], 1, "Preview (1 lines):\n  " + long[:previewWidth] + "\n"},
+	}
+	for _, tt := range tests {
+		var b strings.Builder
+		writePreview(&b, tt.content, tt.n)
+		if b.String() != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, b.String(), tt.want)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// unescapeDelim interprets Go/C-style escapes such as \n and \t in a
+// delimiter given on the command line. Delimiters that aren't valid escape
+// sequences are used literally.
+func unescapeDelim(d string) string {
+	if u, err := strconv.Unquote(`"` + d + `"`); err == nil {
+		return u
+	}
+	return d
+}
+
+// splitSegments splits s on delim, dropping segments that are blank.
+func splitSegments(s, delim string) []string {
+	var segs []string
+	for _, seg := range strings.Split(s, delim) {
+		if strings.TrimSpace(seg) != "" {
+			segs = append(segs, seg)
+		}
+	}
+	return segs
+}
+
+// rotateCopy copies each segment in turn with write, pausing delay between
+// them, and announces the active segment on status.
+func rotateCopy(segs []string, delay time.Duration, write func(string) error, status io.Writer) error {
+	for i, seg := range segs {
+		if err := write(seg); err != nil {
+			return fmt.Errorf("segment %d: %w", i+1, err)
+		}
+		fmt.Fprintf(status, "[%d/%d] %s\n", i+1, len(segs), truncateMiddle(firstLine(seg), 60))
+		if i < len(segs)-1 {
+			time.Sleep(delay)
+		}
+	}
+	return nil
+}
+
+// firstLine returns s up to its first newline.
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(s, "\n")
+	return line
+}
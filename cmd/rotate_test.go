@@ -0,0 +1,115 @@
+package main
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestUnescapeDelim(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{`\n`, "\n"},
+		{`\t`, "\t"},
+		{`\n---\n`, "\n---\n"},
+		{`,`, ","},
+		{`\x00`, "\x00"},
+		{`\q`, `\q`}, // not an escape, used literally
+		{`"`, `"`},
+		{``, ``},
+	}
+	for _, tt := range tests {
+		if got := unescapeDelim(tt.in); got != tt.want {
+			t.Errorf("unescapeDelim(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestSplitSegments(t *testing.T) {
+	tests := []struct {
+		in, delim string
+		want      []string
+	}{
+		{"a\n---\nb\n---\nc", "\n---\n", []string{"a", "b", "c"}},
+		{"a,,b, ,c,", ",", []string{"a", "b", "c"}},
+		{"one\n\ntwo\n", "\n\n", []string{"one", "two\n"}},
+		{"no delimiter", ";", []string{"no delimiter"}},
+		{"  \n", ";", nil},
+		{"", ";", nil},
+	}
+	for _, tt := range tests {
+		if got := splitSegments(tt.in, tt.delim); !slices.Equal(got, tt.want) {
+			t.Errorf("splitSegments(%q, %q) = %q, want %q", tt.in, tt.delim, got, tt.want)
+		}
+	}
+}
+
+func TestRotateCopy(t *testing.T) {
+	errFull := errors.New("clipboard busy")
+	tests := []struct {
+		name       string
+		segs       []string
+		failAt     int // 1-based write that fails; 0 for none
+		wantWrites []string
+		wantStatus string
+		wantErr    string
+	}{
+		{
+			name:       "all segments",
+			segs:       []string{"first", "second\nmore", "third"},
+			wantWrites: []string{"first", "second\nmore", "third"},
+			wantStatus: "[1/3] first\n[2/3] second\n[3/3] third\n",
+		},
+		{
+			name:       "one segment",
+			segs:       []string{"only"},
+			wantWrites: []string{"only"},
+			wantStatus: "[1/1] only\n",
+		},
+		{
+			name:       "stops at a failed write",
+			segs:       []string{"a", "b", "c"},
+			failAt:     2,
+			wantWrites: []string{"a", "b"},
+			wantStatus: "[1/3] a\n",
+			wantErr:    "segment 2: clipboard busy",
+		},
+		{
+			name: "no segments",
+		},
+	}
+	const delay = 5 * time.Millisecond
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var writes []string
+			write := func(s string) error {
+				writes = append(writes, s)
+				if len(writes) == tt.failAt {
+					return errFull
+				}
+				return nil
+			}
+			var status strings.Builder
+			start := time.Now()
+			err := rotateCopy(tt.segs, delay, write, &status)
+			elapsed := time.Since(start)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr || !errors.Is(err, errFull) {
+					t.Errorf("err = %v, want %q", err, tt.wantErr)
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(writes, tt.wantWrites) {
+				t.Errorf("writes %q, want %q", writes, tt.wantWrites)
+			}
+			if status.String() != tt.wantStatus {
+				t.Errorf("status %q, want %q", status.String(), tt.wantStatus)
+			}
+			if pauses := max(len(writes)-1, 0); elapsed < time.Duration(pauses)*delay {
+				t.Errorf("took %v for %d pauses of %v", elapsed, pauses, delay)
+			}
+		})
+	}
+}
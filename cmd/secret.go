@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// secretClearAfter is how long --secret leaves the content on the clipboard
+// unless --clear-after says otherwise.
+const secretClearAfter = 45 * time.Second
+
+// clearClipboardAfter waits d and then empties the clipboard, unless it no
+// longer holds content because something else has been copied since. If the
+// clipboard can't be read it is cleared anyway.
+func clearClipboardAfter(d time.Duration, content string, opts clipOptions, status io.Writer) error {
+	fmt.Fprintf(status, "Clearing the clipboard in %s (Ctrl-C to keep it).\n", d)
+	time.Sleep(d)
+	if current, err := readClipboard(false); err == nil && current != content {
+		fmt.Fprintln(status, "Clipboard has changed; leaving it alone.")
+		return nil
+	}
+	if err := writeToClipboard("", opts); err != nil {
+		return err
+	}
+	fmt.Fprintln(status, "Clipboard cleared.")
+	return nil
+}
+
+// secretPatterns are the kinds of credentials --warn-secrets looks for, in
+// the order they are reported.
+var secretPatterns = []struct {
+	kind string
+	re   *regexp.Regexp
+}{
+	{"private key", regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----`)},
+	{"AWS access key", regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`)},
+	{"GitHub token", regexp.MustCompile(`\bgh[pousr]_[A-Za-z0-9]{36,}\b`)},
+	{"Slack token", regexp.MustCompile(`\bxox[abprs]-[A-Za-z0-9-]{10,}`)},
+	{"JWT", regexp.MustCompile(`\beyJ[A-Za-z0-9_-]+\.eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+`)},
+	// The name may be part of a longer one, as in DB_PASSWORD or
+	// AWS_SECRET_ACCESS_KEY.
+	{"password or key assignment", regexp.MustCompile(`(?i)\b(?:\w*_)?(?:password|passwd|secret|api[_-]?key|access[_-]?token)(?:_\w*)?\s*[:=]\s*\S+`)},
+}
+
+// describeSecrets returns what secretPatterns find in s, such as
+// "1 AWS access key, 2 JWT", or "" if nothing matches.
+func describeSecrets(s string) string {
+	var found []string
+	for _, p := range secretPatterns {
+		if n := len(p.re.FindAllStringIndex(s, -1)); n > 0 {
+			found = append(found, fmt.Sprintf("%d %s", n, p.kind))
+		}
+	}
+	return strings.Join(found, ", ")
+}
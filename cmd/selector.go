@@ -0,0 +1,182 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// errSelectionCancelled is returned when the user quits the selector.
+var errSelectionCancelled = errors.New("selection cancelled")
+
+// Keys understood by lineSelector.handleKey.
+const (
+	keyUp = iota + 1
+	keyDown
+	keyToggle
+	keyToggleAll
+	keyConfirm
+	keyCancel
+)
+
+// lineSelector holds the state of the interactive line picker, independent
+// of the terminal it is drawn on.
+type lineSelector struct {
+	lines    []string
+	selected []bool
+	cursor   int
+}
+
+func newLineSelector(lines []string) *lineSelector {
+	return &lineSelector{lines: lines, selected: make([]bool, len(lines))}
+}
+
+// handleKey applies a key to the selector and reports whether the selection
+// is finished.
+func (ls *lineSelector) handleKey(key int) (done bool, err error) {
+	switch key {
+	case keyUp:
+		if ls.cursor > 0 {
+			ls.cursor--
+		}
+	case keyDown:
+		if ls.cursor < len(ls.lines)-1 {
+			ls.cursor++
+		}
+	case keyToggle:
+		if len(ls.lines) > 0 {
+			ls.selected[ls.cursor] = !ls.selected[ls.cursor]
+		}
+	case keyToggleAll:
+		all := true
+		for _, sel := range ls.selected {
+			all = all && sel
+		}
+		for i := range ls.selected {
+			ls.selected[i] = !all
+		}
+	case keyConfirm:
+		return true, nil
+	case keyCancel:
+		return true, errSelectionCancelled
+	}
+	return false, nil
+}
+
+// result returns the selected lines in their original order.
+func (ls *lineSelector) result() []string {
+	var out []string
+	for i, line := range ls.lines {
+		if ls.selected[i] {
+			out = append(out, line)
+		}
+	}
+	return out
+}
+
+// parseKey maps raw terminal input to a selector key, or 0 if unknown.
+func parseKey(b []byte) int {
+	switch string(b) {
+	case "\x1b[A", "\x1bOA", "k":
+		return keyUp
+	case "\x1b[B", "\x1bOB", "j":
+		return keyDown
+	case " ":
+		return keyToggle
+	case "a":
+		return keyToggleAll
+	case "\r", "\n":
+		return keyConfirm
+	case "q", "\x1b", "\x03":
+		return keyCancel
+	}
+	return 0
+}
+
+// render draws the visible window of the selector to w.
+func (ls *lineSelector) render(w io.Writer, height int) {
+	var b strings.Builder
+	b.WriteString("\x1b[H\x1b[2J")
+	b.WriteString("goclip: ↑/↓ move, space toggle, a all, enter copy, q cancel\r\n")
+	rows := max(height-1, 1)
+	top := 0
+	if ls.cursor >= rows {
+		top = ls.cursor - rows + 1
+	}
+	for i := top; i < len(ls.lines) && i < top+rows; i++ {
+		cursor, mark := "  ", "[ ]"
+		if i == ls.cursor {
+			cursor = "> "
+		}
+		if ls.selected[i] {
+			mark = "[x]"
+		}
+		fmt.Fprintf(&b, "%s%s %s\r\n", cursor, mark, stripANSI(ls.lines[i]))
+	}
+	_, _ = io.WriteString(w, b.String())
+}
+
+// stty runs stty against tty and returns its trimmed output.
+func stty(tty *os.File, args ...string) (string, error) {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = tty
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("stty %s: %w", strings.Join(args, " "), err)
+	}
+	return string(bytes.TrimSpace(out)), nil
+}
+
+// selectLines lets the user pick lines interactively on /dev/tty. The
+// terminal is put into raw mode with stty and restored afterwards.
+func selectLines(lines []string) ([]string, error) {
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return nil, fmt.Errorf("open /dev/tty: %w", err)
+	}
+	defer tty.Close()
+
+	saved, err := stty(tty, "-g")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := stty(tty, "raw", "-echo"); err != nil {
+		return nil, err
+	}
+	defer func() { _, _ = stty(tty, saved) }()
+
+	height := 24
+	if size, err := stty(tty, "size"); err == nil {
+		if rows, _, ok := strings.Cut(size, " "); ok {
+			if n, err := strconv.Atoi(rows); err == nil && n > 0 {
+				height = n
+			}
+		}
+	}
+
+	// Use the alternate screen so the picker leaves no trace behind.
+	_, _ = io.WriteString(tty, "\x1b[?1049h\x1b[?25l")
+	defer func() { _, _ = io.WriteString(tty, "\x1b[?25h\x1b[?1049l") }()
+
+	ls := newLineSelector(lines)
+	buf := make([]byte, 16)
+	for {
+		ls.render(tty, height)
+		n, err := tty.Read(buf)
+		if err != nil {
+			return nil, fmt.Errorf("read /dev/tty: %w", err)
+		}
+		done, err := ls.handleKey(parseKey(buf[:n]))
+		if err != nil {
+			return nil, err
+		}
+		if done {
+			return ls.result(), nil
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestLineSelector(t *testing.T) {
+	lines := []string{"one", "two", "three"}
+	tests := []struct {
+		name    string
+		keys    []int
+		want    []string
+		wantErr error
+	}{
+		{"confirm with nothing selected", []int{keyConfirm}, nil, nil},
+		{"toggle first", []int{keyToggle, keyConfirm}, []string{"one"}, nil},
+		{"move and toggle", []int{keyDown, keyDown, keyToggle, keyUp, keyToggle, keyConfirm}, []string{"two", "three"}, nil},
+		{"toggle twice deselects", []int{keyToggle, keyToggle, keyConfirm}, nil, nil},
+		{"cursor stops at the top", []int{keyUp, keyUp, keyToggle, keyConfirm}, []string{"one"}, nil},
+		{"cursor stops at the bottom", []int{keyDown, keyDown, keyDown, keyDown, keyToggle, keyConfirm}, []string{"three"}, nil},
+		{"select all", []int{keyToggleAll, keyConfirm}, lines, nil},
+		{"select all after a partial selection", []int{keyToggle, keyToggleAll, keyConfirm}, lines, nil},
+		{"toggle all twice clears", []int{keyToggleAll, keyToggleAll, keyConfirm}, nil, nil},
+		{"result keeps input order", []int{keyDown, keyDown, keyToggle, keyUp, keyUp, keyToggle, keyConfirm}, []string{"one", "three"}, nil},
+		{"unknown keys ignored", []int{0, 99, keyToggle, keyConfirm}, []string{"one"}, nil},
+		{"cancel", []int{keyToggle, keyCancel}, nil, errSelectionCancelled},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ls := newLineSelector(lines)
+			var err error
+			done := false
+			for i, k := range tt.keys {
+				if done {
+					t.Fatalf("key %d sent after the selector finished", i)
+				}
+				done, err = ls.handleKey(k)
+			}
+			if !done {
+				t.Fatal("selector not finished")
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && !slices.Equal(ls.result(), tt.want) {
+				t.Errorf("result = %q, want %q", ls.result(), tt.want)
+			}
+		})
+	}
+}
+
+func TestLineSelectorEmpty(t *testing.T) {
+	ls := newLineSelector(nil)
+	for _, k := range []int{keyUp, keyDown, keyToggle, keyToggleAll} {
+		if done, err := ls.handleKey(k); done || err != nil {
+			t.Fatalf("handleKey(%d) = %v, %v", k, done, err)
+		}
+	}
+	if got := ls.result(); len(got) != 0 {
+		t.Errorf("result = %q, want none", got)
+	}
+}
+
+func TestParseKey(t *testing.T) {
+	tests := []struct {
+		in   string
+		want int
+	}{
+		{"\x1b[A", keyUp},
+		{"\x1bOA", keyUp},
+		{"k", keyUp},
+		{"\x1b[B", keyDown},
+		{"j", keyDown},
+		{" ", keyToggle},
+		{"a", keyToggleAll},
+		{"\r", keyConfirm},
+		{"\n", keyConfirm},
+		{"q", keyCancel},
+		{"\x1b", keyCancel},
+		{"\x03", keyCancel},
+		{"x", 0},
+		{"\x1b[C", 0},
+	}
+	for _, tt := range tests {
+		if got := parseKey([]byte(tt.in)); got != tt.want {
+			t.Errorf("parseKey(%q) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestLineSelectorRender(t *testing.T) {
+	ls := newLineSelector([]string{"a", "\x1b[31mred\x1b[0m", "c", "d"})
+	ls.handleKey(keyDown)
+	ls.handleKey(keyToggle)
+	ls.handleKey(keyDown)
+	ls.handleKey(keyDown)
+	var out strings.Builder
+	ls.render(&out, 3) // header plus two rows: the window scrolls to the cursor
+	got := out.String()
+	for _, want := range []string{"  [ ] c\r\n", "> [ ] d\r\n"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("render missing %q in %q", want, got)
+		}
+	}
+	if strings.Contains(got, "[x] red") || strings.Contains(got, " a\r\n") {
+		t.Errorf("render shows rows outside the window: %q", got)
+	}
+	out.Reset()
+	ls.handleKey(keyUp)
+	ls.handleKey(keyUp)
+	ls.render(&out, 10)
+	if !strings.Contains(out.String(), "> [x] red\r\n") {
+		t.Errorf("selected row not marked or escapes kept: %q", out.String())
+	}
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"fmt"
+	"unicode/utf8"
+)
+
+// formatBytes renders n as a byte count, or with human in KB/MB (powers of
+// 1024) once it reaches 1 KB.
+func formatBytes(n int, human bool) string {
+	switch {
+	case n == 1:
+		return "1 byte"
+	case !human || n < 1<<10:
+		return fmt.Sprintf("%d bytes", n)
+	case float64(n)/(1<<10) < 1023.95: // would round to 1024.0 KB
+		return fmt.Sprintf("%.1f KB", float64(n)/(1<<10))
+	default:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1<<20))
+	}
+}
+
+// describeSize reports the size of s for status messages, adding the
+// character count when multibyte text makes it differ from the byte count.
+func describeSize(s string, human bool) string {
+	size := formatBytes(len(s), human)
+	switch n := utf8.RuneCountInString(s); {
+	case n == len(s):
+	case n == 1:
+		size += " (1 character)"
+	default:
+		size += fmt.Sprintf(" (%d characters)", n)
+	}
+	return size
+}
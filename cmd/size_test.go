@@ -0,0 +1,48 @@
+package main
+
+import "testing"
+
+func TestFormatBytes(t *testing.T) {
+	tests := []struct {
+		n     int
+		human bool
+		want  string
+	}{
+		{0, false, "0 bytes"},
+		{1, false, "1 byte"},
+		{1, true, "1 byte"},
+		{1023, false, "1023 bytes"},
+		{1023, true, "1023 bytes"},
+		{1024, false, "1024 bytes"},
+		{1024, true, "1.0 KB"},
+		{1536, true, "1.5 KB"},
+		{1048575, true, "1.0 MB"}, // not "1024.0 KB"
+		{1048524, true, "1023.9 KB"},
+		{1048576, false, "1048576 bytes"},
+		{1048576, true, "1.0 MB"},
+		{5 << 20, true, "5.0 MB"},
+	}
+	for _, tt := range tests {
+		if got := formatBytes(tt.n, tt.human); got != tt.want {
+			t.Errorf("formatBytes(%d, %v) = %q, want %q", tt.n, tt.human, got, tt.want)
+		}
+	}
+}
+
+func TestDescribeSize(t *testing.T) {
+	tests := []struct {
+		s     string
+		human bool
+		want  string
+	}{
+		{"hello", false, "5 bytes"},
+		{"héllo", false, "6 bytes (5 characters)"},
+		{"🙂", false, "4 bytes (1 character)"},
+		{"", false, "0 bytes"},
+	}
+	for _, tt := range tests {
+		if got := describeSize(tt.s, tt.human); got != tt.want {
+			t.Errorf("describeSize(%q) = %q, want %q", tt.s, got, tt.want)
+		}
+	}
+}
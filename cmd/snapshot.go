@@ -0,0 +1,68 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// snapshotLayout names snapshot files so they sort chronologically.
+const snapshotLayout = "20060102-150405"
+
+// writeSnapshot saves content to a new file in dir named after t, adding a
+// numeric suffix if a snapshot from the same second exists. Snapshots may
+// hold secrets, so they are readable by the owner only.
+func writeSnapshot(dir, content string, t time.Time) (string, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", err
+	}
+	base := "clipboard-" + t.Format(snapshotLayout)
+	for i := 0; ; i++ {
+		name := base + ".txt"
+		if i > 0 {
+			name = fmt.Sprintf("%s-%d.txt", base, i)
+		}
+		path := filepath.Join(dir, name)
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+		if errors.Is(err, fs.ErrExist) {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		if _, err := f.WriteString(content); err != nil {
+			f.Close()
+			return "", err
+		}
+		return path, f.Close()
+	}
+}
+
+// runSnapshot implements the snapshot command: save the current clipboard
+// to a timestamped file before it is overwritten.
+func runSnapshot(args []string, quiet, human bool) error {
+	fs := flag.NewFlagSet("snapshot", flag.ExitOnError)
+	dir := fs.String("dir", ".", "directory to write the snapshot to (created if missing)")
+	primary := fs.Bool("primary", false, "save the primary selection instead of the clipboard")
+	_ = fs.Parse(args)
+
+	content, err := readClipboard(*primary)
+	if err != nil {
+		return err
+	}
+	if content == "" {
+		return fmt.Errorf("clipboard is empty")
+	}
+	path, err := writeSnapshot(*dir, content, time.Now())
+	if err != nil {
+		return err
+	}
+	if !quiet {
+		fmt.Fprintf(os.Stderr, "Saved %s to %s.\n", describeSize(content, human), path)
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteSnapshot(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "snaps", "nested")
+	at := time.Date(2024, 3, 9, 14, 5, 7, 0, time.Local)
+	wantNames := []string{"clipboard-20240309-140507.txt", "clipboard-20240309-140507-1.txt", "clipboard-20240309-140507-2.txt"}
+	for i, want := range wantNames {
+		content := strings.Repeat("x", i) + "snapshot\n"
+		path, err := writeSnapshot(dir, content, at)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if filepath.Base(path) != want {
+			t.Errorf("snapshot %d named %s, want %s", i, filepath.Base(path), want)
+		}
+		got, _ := os.ReadFile(path)
+		if string(got) != content {
+			t.Errorf("snapshot %d holds %q, want %q", i, got, content)
+		}
+		if fi, err := os.Stat(path); err == nil && runtime.GOOS != "windows" && fi.Mode().Perm() != 0o600 {
+			t.Errorf("snapshot mode %v, want 0600", fi.Mode().Perm())
+		}
+	}
+}
+
+// TestRunSnapshot saves a fake clipboard with the snapshot command.
+func TestRunSnapshot(t *testing.T) {
+	tests := []struct {
+		name      string
+		args      []string
+		clipboard string
+		wantErr   string
+		wantArgs  string
+	}{
+		{"clipboard", nil, "saved text\n", "", "--no-newline"},
+		{"primary", []string{"--primary"}, "selected", "", "--no-newline --primary"},
+		{"empty clipboard", nil, "", "clipboard is empty", "--no-newline"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := isolateClipboard(t)
+			t.Setenv("WAYLAND_DISPLAY", "wayland-0")
+			fakePaster(t, dir, "wl-paste", tt.clipboard, 0)
+			out := filepath.Join(dir, "snaps")
+			err := runSnapshot(append([]string{"--dir", out}, tt.args...), true, false)
+			if got := helperArgs(dir, "wl-paste"); got != tt.wantArgs {
+				t.Errorf("wl-paste args %q, want %q", got, tt.wantArgs)
+			}
+			files, _ := filepath.Glob(filepath.Join(out, "clipboard-*.txt"))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("err = %v, want %q", err, tt.wantErr)
+				}
+				if len(files) != 0 {
+					t.Errorf("wrote %v after an error", files)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(files) != 1 {
+				t.Fatalf("snapshots %v, want one", files)
+			}
+			if got, _ := os.ReadFile(files[0]); string(got) != tt.clipboard {
+				t.Errorf("snapshot holds %q, want %q", got, tt.clipboard)
+			}
+		})
+	}
+}
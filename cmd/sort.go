@@ -0,0 +1,95 @@
+package main
+
+import (
+	"cmp"
+	"math/rand/v2"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// sortOptions selects how sortLines orders lines.
+type sortOptions struct {
+	reverse    bool
+	numeric    bool
+	unique     bool // drop lines that compare equal, keeping the first
+	ignoreCase bool // compare case-folded text
+}
+
+var leadingNumberRE = regexp.MustCompile(`^\s*[-+]?(\d+(\.\d*)?|\.\d+)`)
+
+// leadingNumber parses the number at the start of line, as sort -n does.
+func leadingNumber(line string) (float64, bool) {
+	m := leadingNumberRE.FindString(line)
+	if m == "" {
+		return 0, false
+	}
+	n, err := strconv.ParseFloat(strings.TrimSpace(m), 64)
+	return n, err == nil
+}
+
+// compareKeys orders a and b by their sort keys: the whole line or, with
+// numeric, the leading number. Lines without a number sort before those
+// with one and share a key.
+func compareKeys(a, b string, opts sortOptions) int {
+	if opts.ignoreCase {
+		a, b = strings.ToLower(a), strings.ToLower(b)
+	}
+	if !opts.numeric {
+		return strings.Compare(a, b)
+	}
+	na, okA := leadingNumber(a)
+	nb, okB := leadingNumber(b)
+	switch {
+	case okA && okB:
+		return cmp.Compare(na, nb)
+	case okA:
+		return 1
+	case okB:
+		return -1
+	}
+	return 0
+}
+
+// compareLines orders a and b by compareKeys, falling back to lexical
+// order for ties.
+func compareLines(a, b string, opts sortOptions) int {
+	if c := compareKeys(a, b, opts); c != 0 {
+		return c
+	}
+	if opts.ignoreCase {
+		a, b = strings.ToLower(a), strings.ToLower(b)
+	}
+	return strings.Compare(a, b)
+}
+
+// sortLines sorts the lines of s. The sort is stable, so lines that compare
+// equal keep their order. With unique, lines are compared by key alone and
+// the first line with each key is kept, as with sort -u.
+func sortLines(s string, opts sortOptions) string {
+	lines, trailingNL := splitLines(s)
+	order := compareLines
+	if opts.unique {
+		order = compareKeys
+	}
+	compare := func(a, b string) int {
+		c := order(a, b, opts)
+		if opts.reverse {
+			return -c
+		}
+		return c
+	}
+	slices.SortStableFunc(lines, compare)
+	if opts.unique {
+		lines = slices.CompactFunc(lines, func(a, b string) bool { return compare(a, b) == 0 })
+	}
+	return joinLines(lines, trailingNL)
+}
+
+// shuffleLines puts the lines of s in random order drawn from r.
+func shuffleLines(s string, r *rand.Rand) string {
+	lines, trailingNL := splitLines(s)
+	r.Shuffle(len(lines), func(i, j int) { lines[i], lines[j] = lines[j], lines[i] })
+	return joinLines(lines, trailingNL)
+}
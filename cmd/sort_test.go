@@ -0,0 +1,110 @@
+package main
+
+import (
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestSortLines(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		opts sortOptions
+		want string
+	}{
+		{"lexical", "pear\napple\nBanana\nfig\n", sortOptions{}, "Banana\napple\nfig\npear\n"},
+		{"reverse", "pear\napple\nBanana\nfig\n", sortOptions{reverse: true}, "pear\nfig\napple\nBanana\n"},
+		{"numeric", "10 ten\n9 nine\n100 hundred\n-1 minus\n", sortOptions{numeric: true}, "-1 minus\n9 nine\n10 ten\n100 hundred\n"},
+		{"numeric decimals", "1.5\n.5\n1.25\n", sortOptions{numeric: true}, ".5\n1.25\n1.5\n"},
+		{"numeric with text lines", "b\n10\nzz\n2\na\n", sortOptions{numeric: true}, "a\nb\nzz\n2\n10\n"},
+		{"numeric ties lexical", "5 b\n5 a\n3 z\n", sortOptions{numeric: true}, "3 z\n5 a\n5 b\n"},
+		{"numeric reverse", "1\n3\n2\n", sortOptions{numeric: true, reverse: true}, "3\n2\n1\n"},
+		{"lexical is not numeric", "10\n9\n", sortOptions{}, "10\n9\n"},
+		{"unterminated input", "b\na", sortOptions{}, "a\nb"},
+		{"empty", "", sortOptions{}, ""},
+	}
+	for _, tt := range tests {
+		if got := sortLines(tt.in, tt.opts); got != tt.want {
+			t.Errorf("%s: sortLines(%q) = %q, want %q", tt.name, tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestShuffleLines(t *testing.T) {
+	const in = "a\nb\nc\nd\ne\nf\ng\nh\n"
+	shuffle := func(seed uint64) string {
+		return shuffleLines(in, rand.New(rand.NewPCG(seed, seed)))
+	}
+	tests := []struct {
+		seed uint64
+	}{{0}, {1}, {42}, {1 << 40}}
+	for _, tt := range tests {
+		got := shuffle(tt.seed)
+		if again := shuffle(tt.seed); got != again {
+			t.Errorf("seed %d: %q then %q", tt.seed, got, again)
+		}
+		lines, trailingNL := splitLines(got)
+		slices.Sort(lines)
+		if joinLines(lines, trailingNL) != in {
+			t.Errorf("seed %d: %q is not a permutation of the input", tt.seed, got)
+		}
+	}
+	if shuffle(1) == shuffle(2) && shuffle(2) == shuffle(3) {
+		t.Error("different seeds give the same order")
+	}
+}
+
+// TestShuffleSeed runs goclip --shuffle --seed twice and checks the order
+// is repeatable.
+func TestShuffleSeed(t *testing.T) {
+	const in = "1\n2\n3\n4\n5\n6\n7\n8\n9\n"
+	var outs []string
+	for range 2 {
+		out := filepath.Join(t.TempDir(), "out.txt")
+		if _, stderr, err := runGoclip(t, in, "-q", "--no-clip", "-f", out, "--shuffle", "--seed", "7"); err != nil {
+			t.Fatalf("goclip: %v\n%s", err, stderr)
+		}
+		b, _ := os.ReadFile(out)
+		outs = append(outs, string(b))
+	}
+	if outs[0] != outs[1] || outs[0] == in {
+		t.Errorf("--seed 7 gave %q then %q", outs[0], outs[1])
+	}
+}
+
+func TestSortUnique(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		opts sortOptions
+		want string
+	}{
+		{"scattered duplicates", "b\na\nc\na\nb\na\n", sortOptions{unique: true}, "a\nb\nc\n"},
+		{"case differs", "b\nB\na\nA\nb\n", sortOptions{unique: true}, "A\nB\na\nb\n"},
+		{"ignoring case keeps the first", "b\nApple\nB\napple\nAPPLE\n", sortOptions{unique: true, ignoreCase: true}, "Apple\nb\n"},
+		{"numeric duplicates", "10\n2\n10\n2\n", sortOptions{unique: true, numeric: true}, "2\n10\n"},
+		{"numeric equal numbers", "1 a\n1 b\n", sortOptions{unique: true, numeric: true}, "1 a\n"},
+		{"numeric keeps the first", "2 x\n1 b\n1 a\n", sortOptions{unique: true, numeric: true}, "1 b\n2 x\n"},
+		{"numeric equal values", "1.0 one\n1 one\n01\n", sortOptions{unique: true, numeric: true}, "1.0 one\n"},
+		{"reverse", "a\nc\na\nb\n", sortOptions{unique: true, reverse: true}, "c\nb\na\n"},
+		{"no duplicates", "b\na\n", sortOptions{unique: true}, "a\nb\n"},
+	}
+	for _, tt := range tests {
+		if got := sortLines(tt.in, tt.opts); got != tt.want {
+			t.Errorf("%s: sortLines(%q) = %q, want %q", tt.name, tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestSortNumericUniqueFlags(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "out.txt")
+	if _, stderr, err := runGoclip(t, "2 c\n1 a\n1 b\n", "-q", "--no-clip", "-f", out, "--sort-numeric", "--sort-unique"); err != nil {
+		t.Fatalf("goclip: %v\n%s", err, stderr)
+	}
+	if got, _ := os.ReadFile(out); string(got) != "1 a\n2 c\n" {
+		t.Errorf("got %q, want %q", got, "1 a\n2 c\n")
+	}
+}
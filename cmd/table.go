@@ -0,0 +1,135 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// convertDelimited parses s as delimiter-separated records using from and
+// re-emits them separated by to, quoting fields as needed. Rows may have
+// differing numbers of fields.
+func convertDelimited(s string, from, to rune) (string, error) {
+	r := csv.NewReader(strings.NewReader(s))
+	r.Comma = from
+	r.FieldsPerRecord = -1
+	records, err := r.ReadAll()
+	if err != nil {
+		return "", fmt.Errorf("parse input: %w", err)
+	}
+
+	var b strings.Builder
+	w := csv.NewWriter(&b)
+	w.Comma = to
+	if err := w.WriteAll(records); err != nil {
+		return "", fmt.Errorf("write output: %w", err)
+	}
+	return b.String(), nil
+}
+
+// alignGap separates columns aligned by alignColumns.
+const alignGap = "  "
+
+// alignColumns splits each line of s on runs of whitespace and pads the
+// fields so the columns line up. Rows with fewer fields simply end early;
+// blank lines are kept. Columns whose cells below the first row are all
+// numbers are right-aligned, as ls and df do for sizes; the first row may be
+// a header.
+func alignColumns(s string) string {
+	lines, trailingNL := splitLines(s)
+	rows := make([][]string, len(lines))
+	var widths []int
+	var numeric []bool
+	for i, line := range lines {
+		rows[i] = strings.Fields(line)
+		for j, cell := range rows[i] {
+			if j == len(widths) {
+				widths = append(widths, 0)
+				numeric = append(numeric, true)
+			}
+			widths[j] = max(widths[j], displayWidth(cell))
+			if _, err := strconv.ParseFloat(cell, 64); err != nil && i > 0 {
+				numeric[j] = false
+			}
+		}
+	}
+
+	for i, row := range rows {
+		var b strings.Builder
+		for j, cell := range row {
+			if j > 0 {
+				b.WriteString(alignGap)
+			}
+			pad := strings.Repeat(" ", widths[j]-displayWidth(cell))
+			switch {
+			case numeric[j]:
+				b.WriteString(pad + cell)
+			case j < len(row)-1:
+				b.WriteString(cell + pad)
+			default:
+				b.WriteString(cell) // no trailing padding
+			}
+		}
+		lines[i] = b.String()
+	}
+	return joinLines(lines, trailingNL)
+}
+
+// markdownTable converts s into a GitHub-flavored Markdown table with the
+// first line as the header. Fields are split on tabs if the input has any,
+// otherwise on runs of whitespace. Short rows are padded with empty cells;
+// blank lines are skipped.
+func markdownTable(s string) string {
+	lines, _ := splitLines(s)
+	split := strings.Fields
+	if strings.Contains(s, "\t") {
+		split = func(line string) []string { return strings.Split(line, "\t") }
+	}
+	var rows [][]string
+	cols := 0
+	for _, line := range lines {
+		line = strings.TrimSuffix(line, "\r")
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		row := split(line)
+		for j, cell := range row {
+			row[j] = strings.ReplaceAll(strings.TrimSpace(cell), "|", `\|`)
+		}
+		rows = append(rows, row)
+		cols = max(cols, len(row))
+	}
+	if len(rows) == 0 {
+		return ""
+	}
+
+	widths := make([]int, cols)
+	for i := range rows {
+		for len(rows[i]) < cols {
+			rows[i] = append(rows[i], "")
+		}
+		for j, cell := range rows[i] {
+			widths[j] = max(widths[j], displayWidth(cell), 3)
+		}
+	}
+
+	var b strings.Builder
+	writeRow := func(row []string) {
+		b.WriteString("|")
+		for j, cell := range row {
+			b.WriteString(" " + cell + strings.Repeat(" ", widths[j]-displayWidth(cell)) + " |")
+		}
+		b.WriteString("\n")
+	}
+	writeRow(rows[0])
+	b.WriteString("|")
+	for _, w := range widths {
+		b.WriteString(" " + strings.Repeat("-", w) + " |")
+	}
+	b.WriteString("\n")
+	for _, row := range rows[1:] {
+		writeRow(row)
+	}
+	return b.String()
+}
@@ -0,0 +1,111 @@
+package main
+
+import "testing"
+
+func TestConvertDelimited(t *testing.T) {
+	tests := []struct {
+		name     string
+		in       string
+		from, to rune
+		want     string
+	}{
+		{"tsv to csv", "a\tb\tc\n1\t2\t3\n", '\t', ',', "a,b,c\n1,2,3\n"},
+		{"csv to tsv", "a,b,c\n1,2,3\n", ',', '\t', "a\tb\tc\n1\t2\t3\n"},
+		{"comma in a tsv field is quoted", "name\tcity\nDoe, J.\tParis\n", '\t', ',', "name,city\n\"Doe, J.\",Paris\n"},
+		{"tab in a csv field is quoted", "\"a\tb\",c\n", ',', '\t', "\"a\tb\"\tc\n"},
+		{"quoted tsv field", "\"a\tb\"\tc\n", '\t', ',', "a\tb,c\n"},
+		{"quotes stay doubled", "\"say \"\"hi\"\"\",x\n", ',', '\t', "\"say \"\"hi\"\"\"\tx\n"},
+		{"embedded newline", "\"line one\nline two\",x\n", ',', '\t', "\"line one\nline two\"\tx\n"},
+		{"quoted comma unquoted in tsv", "\"a,b\",c\n", ',', '\t', "a,b\tc\n"},
+		{"ragged rows", "a,b,c\n1\n", ',', '\t', "a\tb\tc\n1\n"},
+		{"no trailing newline", "a\tb", '\t', ',', "a,b\n"},
+		{"empty fields", "a,,c\n", ',', '\t', "a\t\tc\n"},
+		{"empty input", "", ',', '\t', ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := convertDelimited(tt.in, tt.from, tt.to)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("convertDelimited(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+			back, err := convertDelimited(got, tt.to, tt.from)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if again, _ := convertDelimited(back, tt.from, tt.to); again != got {
+				t.Errorf("round trip changed %q to %q", got, again)
+			}
+		})
+	}
+}
+
+func TestConvertDelimitedInvalid(t *testing.T) {
+	for _, in := range []string{
+		"\"unterminated,field\n",
+		"a,b\"c\n",
+	} {
+		if got, err := convertDelimited(in, ',', '\t'); err == nil {
+			t.Errorf("convertDelimited(%q) = %q, want an error", in, got)
+		}
+	}
+}
+
+func TestAlignColumns(t *testing.T) {
+	tests := []struct {
+		name, in, want string
+	}{
+		{"ragged columns",
+			"a bb ccc\ndddd e f\n",
+			"a     bb  ccc\ndddd  e   f\n"},
+		{"numbers right-aligned under a header",
+			"NAME SIZE\nfoo 5\nlongname 1200\n",
+			"NAME      SIZE\nfoo          5\nlongname  1200\n"},
+		{"short rows end early",
+			"one two three\nfour\nfive six\n",
+			"one   two  three\nfour\nfive  six\n"},
+		{"blank lines kept",
+			"a b\n\nccc d",
+			"a    b\n\nccc  d"},
+		{"wide characters",
+			"名前 x\nab y\n",
+			"名前  x\nab    y\n"},
+		{"empty", "", ""},
+	}
+	for _, tt := range tests {
+		if got := alignColumns(tt.in); got != tt.want {
+			t.Errorf("%s: alignColumns(%q) =\n%q, want\n%q", tt.name, tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestMarkdownTable(t *testing.T) {
+	tests := []struct {
+		name, in, want string
+	}{
+		{"small table",
+			"name size\nfoo 12\nbar 3\n",
+			"| name | size |\n| ---- | ---- |\n| foo  | 12   |\n| bar  | 3    |\n"},
+		{"short rows padded",
+			"a b c\n1\n2 3\n",
+			"| a   | b   | c   |\n| --- | --- | --- |\n| 1   |     |     |\n| 2   | 3   |     |\n"},
+		{"tabs keep spaces in cells",
+			"first name\tcity\r\nAda Lovelace\tLondon\r\n",
+			"| first name   | city   |\n| ------------ | ------ |\n| Ada Lovelace | London |\n"},
+		{"pipes escaped",
+			"expr\na|b\n",
+			"| expr |\n| ---- |\n| a\\|b |\n"},
+		{"blank lines skipped",
+			"h\n\nv\n",
+			"| h   |\n| --- |\n| v   |\n"},
+		{"header only", "x y", "| x   | y   |\n| --- | --- |\n"},
+		{"empty", "\n\n", ""},
+	}
+	for _, tt := range tests {
+		if got := markdownTable(tt.in); got != tt.want {
+			t.Errorf("%s: markdownTable(%q) =\n%s\nwant\n%s", tt.name, tt.in, got, tt.want)
+		}
+	}
+}
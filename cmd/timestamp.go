@@ -0,0 +1,63 @@
+package main
+
+import (
+	"regexp"
+	"time"
+)
+
+// leadingTimeRE captures a timestamp at the start of a log line: ISO 8601 /
+// RFC 3339 (optionally bracketed), Apache common log format, or syslog.
+var leadingTimeRE = regexp.MustCompile(
+	`^\s*\[?(\d{4}-\d{2}-\d{2}[T ]\d{2}:\d{2}:\d{2}(?:[.,]\d+)?(?:Z|[+-]\d{2}:?\d{2})?|\d{2}/[A-Z][a-z]{2}/\d{4}:\d{2}:\d{2}:\d{2} [+-]\d{4}|[A-Z][a-z]{2} [ \d]\d \d{2}:\d{2}:\d{2})`,
+)
+
+// timeLayouts are tried in order against the captured timestamp. Layouts
+// without a zone are interpreted in local time.
+var timeLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05Z0700",
+	"2006-01-02 15:04:05Z07:00",
+	"2006-01-02 15:04:05Z0700",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"02/Jan/2006:15:04:05 -0700",
+	time.Stamp,
+}
+
+// parseLeadingTime extracts the timestamp a log line starts with. Syslog
+// stamps carry no year, so now's year is assumed (or the previous one if
+// that would put the line in the future).
+func parseLeadingTime(line string, now time.Time) (time.Time, bool) {
+	m := leadingTimeRE.FindStringSubmatch(line)
+	if m == nil {
+		return time.Time{}, false
+	}
+	for _, layout := range timeLayouts {
+		t, err := time.ParseInLocation(layout, m[1], time.Local)
+		if err != nil {
+			continue
+		}
+		if layout == time.Stamp {
+			t = t.AddDate(now.Year(), 0, 0)
+			if t.After(now.Add(24 * time.Hour)) {
+				t = t.AddDate(-1, 0, 0)
+			}
+		}
+		return t, true
+	}
+	return time.Time{}, false
+}
+
+// filterSince keeps lines stamped within window of now. Lines without a
+// recognisable timestamp are kept only if keepUntimed is set.
+func filterSince(s string, now time.Time, window time.Duration, keepUntimed bool) string {
+	cutoff := now.Add(-window)
+	out, _ := filterLines(s, func(line string) bool {
+		t, ok := parseLeadingTime(line, now)
+		if !ok {
+			return keepUntimed
+		}
+		return !t.Before(cutoff)
+	})
+	return out
+}
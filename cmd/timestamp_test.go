@@ -0,0 +1,60 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseLeadingTime(t *testing.T) {
+	now := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		line string
+		want time.Time
+		ok   bool
+	}{
+		{"2024-03-10T11:30:00Z started", time.Date(2024, 3, 10, 11, 30, 0, 0, time.UTC), true},
+		{"[2024-03-10T11:30:00.250+01:00] bracketed", time.Date(2024, 3, 10, 10, 30, 0, 250e6, time.UTC), true},
+		{"2024-03-10 11:30:00 space separated", time.Date(2024, 3, 10, 11, 30, 0, 0, time.Local), true},
+		{"  2024-03-10T11:30:00+0000 indented", time.Date(2024, 3, 10, 11, 30, 0, 0, time.UTC), true},
+		{"10/Mar/2024:11:30:00 +0000 GET /", time.Date(2024, 3, 10, 11, 30, 0, 0, time.UTC), true},
+		{"Mar 10 11:30:00 host sshd", time.Date(2024, 3, 10, 11, 30, 0, 0, time.Local), true},
+		{"Dec 31 23:59:59 host last year", time.Date(2023, 12, 31, 23, 59, 59, 0, time.Local), true},
+		{"no timestamp here", time.Time{}, false},
+		{"see 2024-03-10T11:30:00Z later", time.Time{}, false},
+		{"2024-13-45T99:00:00Z invalid", time.Time{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := parseLeadingTime(tt.line, now)
+		if ok != tt.ok || !got.Equal(tt.want) {
+			t.Errorf("parseLeadingTime(%q) = %v, %v; want %v, %v", tt.line, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestFilterSince(t *testing.T) {
+	now := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+	in := "2024-03-10T10:00:00Z old\n" +
+		"untimed continuation\n" +
+		"2024-03-10T11:00:00Z at the cutoff\n" +
+		"2024-03-10T11:45:00Z recent\n" +
+		"  stack frame\n"
+	tests := []struct {
+		name        string
+		window      time.Duration
+		keepUntimed bool
+		want        string
+	}{
+		{"keep untimed", time.Hour, true, "untimed continuation\n2024-03-10T11:00:00Z at the cutoff\n2024-03-10T11:45:00Z recent\n  stack frame\n"},
+		{"drop untimed", time.Hour, false, "2024-03-10T11:00:00Z at the cutoff\n2024-03-10T11:45:00Z recent\n"},
+		{"narrow window", 30 * time.Minute, false, "2024-03-10T11:45:00Z recent\n"},
+		{"nothing recent", time.Minute, false, ""},
+		{"wide window", 24 * time.Hour, false, "2024-03-10T10:00:00Z old\n2024-03-10T11:00:00Z at the cutoff\n2024-03-10T11:45:00Z recent\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := filterSince(in, now, tt.window, tt.keepUntimed); got != tt.want {
+				t.Errorf("filterSince = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
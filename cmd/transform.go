@@ -0,0 +1,150 @@
+package main
+
+import (
+	"io"
+	"mime/quotedprintable"
+	"os"
+	"strings"
+	"unicode"
+)
+
+// truncateMiddle shortens s to at most n display columns by cutting out the
+// middle and joining the kept start and end with an ellipsis. The ellipsis
+// counts towards n.
+func truncateMiddle(s string, n int) string {
+	if n <= 0 || displayWidth(s) <= n {
+		return s
+	}
+	if n == 1 {
+		return "…"
+	}
+	keep := n - 1
+	head := (keep + 1) / 2
+	return headWidth(s, head) + "…" + tailWidth(s, keep-head)
+}
+
+// expandEnv substitutes $VAR and ${VAR} references from the environment,
+// with the same name rules as os.Expand. "$$" yields a literal "$".
+// Undefined variables expand to the empty string unless keepMissing is set,
+// in which case the reference is left exactly as written. A "$" that
+// doesn't start a reference, such as an unclosed "${", is kept as is.
+func expandEnv(s string, keepMissing bool) string {
+	var b strings.Builder
+	for {
+		i := strings.IndexByte(s, '$')
+		if i < 0 {
+			b.WriteString(s)
+			return b.String()
+		}
+		b.WriteString(s[:i])
+		name, w := envRef(s[i:])
+		ref := s[i : i+w]
+		s = s[i+w:]
+		v, ok := os.LookupEnv(name)
+		switch {
+		case name == "":
+			b.WriteString(ref)
+		case name == "$":
+			b.WriteByte('$')
+		case ok:
+			b.WriteString(v)
+		case keepMissing:
+			b.WriteString(ref)
+		}
+	}
+}
+
+// envRef parses the variable reference at the start of s, which begins
+// with '$', and returns the variable name and the length of the reference.
+// The name is empty if the '$' doesn't start a valid reference.
+func envRef(s string) (name string, w int) {
+	if len(s) < 2 {
+		return "", 1
+	}
+	if s[1] == '{' {
+		end := strings.IndexByte(s, '}')
+		if end <= 2 { // unclosed, or "${}"
+			return "", 2
+		}
+		return s[2:end], end + 1
+	}
+	if strings.IndexByte("*#$@!?-0123456789", s[1]) >= 0 { // shell specials, as os.Expand
+		return s[1:2], 2
+	}
+	n := 1
+	for n < len(s) && (s[n] == '_' || isASCIIAlnum(s[n])) {
+		n++
+	}
+	return s[1:n], n
+}
+
+func isASCIIAlnum(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9'
+}
+
+// decodeQuotedPrintable decodes quoted-printable text a line at a time.
+// Sequences the decoder tolerates (such as a stray "=zz") pass through, and a
+// line it rejects outright is kept as it was rather than failing the copy.
+func decodeQuotedPrintable(s string) string {
+	var b strings.Builder
+	for _, line := range strings.SplitAfter(s, "\n") {
+		dec, err := io.ReadAll(quotedprintable.NewReader(strings.NewReader(line)))
+		if err != nil {
+			b.WriteString(line)
+			continue
+		}
+		b.Write(dec)
+	}
+	return b.String()
+}
+
+// shellQuote quotes s as a single POSIX shell word. Each embedded single
+// quote closes the quoting, adds an escaped quote and reopens it; newlines
+// are kept literally, which is valid inside single quotes.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// shellQuoteLines quotes each non-blank line of s as its own shell word and
+// joins them with spaces, ready to paste as command arguments.
+func shellQuoteLines(s string) string {
+	lines, _ := splitLines(s)
+	words := make([]string, 0, len(lines))
+	for _, line := range lines {
+		line = strings.TrimSuffix(line, "\r")
+		if strings.TrimSpace(line) != "" {
+			words = append(words, shellQuote(line))
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// trimModes are the values accepted by --trim.
+var trimModes = []string{"left", "right", "both", "none"}
+
+// changeCase converts s to upper or lower case for --upper and --lower. It
+// uses Go's simple per-rune mappings, so e.g. "ß" has no single-rune upper
+// case and is left as is rather than becoming "SS".
+func changeCase(s string, upper, lower bool) string {
+	switch {
+	case upper:
+		return strings.ToUpper(s)
+	case lower:
+		return strings.ToLower(s)
+	}
+	return s
+}
+
+// trimText removes whitespace from the ends of s selected by mode, one of
+// trimModes.
+func trimText(s, mode string) string {
+	switch mode {
+	case "left":
+		return strings.TrimLeftFunc(s, unicode.IsSpace)
+	case "right":
+		return strings.TrimRightFunc(s, unicode.IsSpace)
+	case "both":
+		return strings.TrimSpace(s)
+	}
+	return s
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"os/exec"
+	"testing"
+)
+
+func TestExpandEnv(t *testing.T) {
+	t.Setenv("GOCLIP_TEST_FOO", "foo")
+	t.Setenv("GOCLIP_TEST_EMPTY", "")
+	tests := []struct {
+		in          string
+		keepMissing bool
+		want        string
+	}{
+		{"$GOCLIP_TEST_FOO", false, "foo"},
+		{"${GOCLIP_TEST_FOO}bar", false, "foobar"},
+		{"x${GOCLIP_TEST_EMPTY}y", true, "xy"},
+		{"a $GOCLIP_TEST_MISSING b", false, "a  b"},
+		{"a $GOCLIP_TEST_MISSING b", true, "a $GOCLIP_TEST_MISSING b"},
+		{"${GOCLIP_TEST_MISSING}bar", true, "${GOCLIP_TEST_MISSING}bar"},
+		{"${GOCLIP_TEST_MISSING}bar", false, "bar"},
+		{"$GOCLIP_TEST_MISSING.txt", true, "$GOCLIP_TEST_MISSING.txt"},
+		{"cost: $$5", false, "cost: $5"},
+		{"$$GOCLIP_TEST_FOO", true, "$GOCLIP_TEST_FOO"},
+		{"price $", false, "price $"},
+		{"$ alone", false, "$ alone"},
+		{"${unclosed", false, "${unclosed"},
+		{"${}", true, "${}"},
+		{"$1st", true, "$1st"},
+		{"$1st", false, "st"},
+		{"${GOCLIP_TEST_FOO}${GOCLIP_TEST_MISSING}$GOCLIP_TEST_FOO", true, "foo${GOCLIP_TEST_MISSING}foo"},
+	}
+	for _, tt := range tests {
+		if got := expandEnv(tt.in, tt.keepMissing); got != tt.want {
+			t.Errorf("expandEnv(%q, %v) = %q, want %q", tt.in, tt.keepMissing, got, tt.want)
+		}
+	}
+}
+
+func TestTruncateMiddle(t *testing.T) {
+	tests := []struct {
+		in   string
+		n    int
+		want string
+	}{
+		{"short", 10, "short"},
+		{"exactly10!", 10, "exactly10!"},
+		{"abcdefghijkl", 5, "ab…kl"},
+		{"abcdefghijkl", 6, "abc…kl"},
+		{"abcdef", 1, "…"},
+		{"abcdef", 0, "abcdef"},
+		{"/home/user/projects/goclip/cmd/main.go", 20, "/home/user…d/main.go"},
+		{"日本語のテキスト", 9, "日本…スト"},
+	}
+	for _, tt := range tests {
+		got := truncateMiddle(tt.in, tt.n)
+		if got != tt.want {
+			t.Errorf("truncateMiddle(%q, %d) = %q, want %q", tt.in, tt.n, got, tt.want)
+		}
+		if tt.n > 0 && displayWidth(got) > tt.n {
+			t.Errorf("truncateMiddle(%q, %d) is %d columns wide", tt.in, tt.n, displayWidth(got))
+		}
+	}
+}
+
+func TestTrimText(t *testing.T) {
+	const in = " \t padded text \n\n"
+	tests := []struct {
+		in, mode, want string
+	}{
+		{in, "left", "padded text \n\n"},
+		{in, "right", " \t padded text"},
+		{in, "both", "padded text"},
+		{in, "none", in},
+		{"\u00a0nbsp\u3000", "both", "nbsp"},
+		{"  inner  spaces  ", "both", "inner  spaces"},
+		{" \n ", "both", ""},
+		{"", "left", ""},
+	}
+	for _, tt := range tests {
+		if got := trimText(tt.in, tt.mode); got != tt.want {
+			t.Errorf("trimText(%q, %s) = %q, want %q", tt.in, tt.mode, got, tt.want)
+		}
+	}
+}
+
+func TestChangeCase(t *testing.T) {
+	tests := []struct {
+		in           string
+		upper, lower bool
+		want         string
+	}{
+		{"Hello, World", true, false, "HELLO, WORLD"},
+		{"Hello, World", false, true, "hello, world"},
+		{"Hello, World", false, false, "Hello, World"},
+		{"élan ÇA ñ", true, false, "ÉLAN ÇA Ñ"},
+		{"ÉLAN ÇA Ñ", false, true, "élan ça ñ"},
+		{"Ωμέγα", true, false, "ΩΜΈΓΑ"},
+		{"ПРИВЕТ", false, true, "привет"},
+		// ß has no single-rune upper case, so it is kept rather than
+		// becoming "SS"; ẞ lowers to ß.
+		{"straße", true, false, "STRAßE"},
+		{"STRAẞE", false, true, "straße"},
+		{"🙂 123\n", true, false, "🙂 123\n"},
+	}
+	for _, tt := range tests {
+		if got := changeCase(tt.in, tt.upper, tt.lower); got != tt.want {
+			t.Errorf("changeCase(%q, %v, %v) = %q, want %q", tt.in, tt.upper, tt.lower, got, tt.want)
+		}
+	}
+}
+
+func TestDecodeQuotedPrintable(t *testing.T) {
+	tests := []struct {
+		name, in, want string
+	}{
+		{"encoded space", "hello=20world", "hello world"},
+		{"encoded UTF-8", "caf=C3=A9 =E2=9C=93", "café ✓"},
+		{"lower-case hex", "caf=c3=a9", "café"},
+		{"soft line break", "a long line that wr=\naps here\n", "a long line that wraps here\n"},
+		{"soft break with CRLF", "wr=\r\naps\r\n", "wraps\r\n"},
+		{"several soft breaks", "one=\ntwo=\nthree", "onetwothree"},
+		{"hard line breaks kept", "line 1\nline 2\n", "line 1\nline 2\n"},
+		{"trailing whitespace dropped", "text   \nnext", "text\nnext"},
+		{"stray equals passes through", "a=zz b", "a=zz b"},
+		{"incomplete escape passes through", "50=4", "50=4"},
+		{"rejected line kept as is", "a=20\x01b\nc=20d", "a=20\x01b\nc d"},
+		{"plain text", "nothing to decode", "nothing to decode"},
+		{"empty", "", ""},
+	}
+	for _, tt := range tests {
+		if got := decodeQuotedPrintable(tt.in); got != tt.want {
+			t.Errorf("%s: decodeQuotedPrintable(%q) = %q, want %q", tt.name, tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestShellQuote(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"plain", "'plain'"},
+		{"with spaces", "'with spaces'"},
+		{"it's", `'it'\''s'`},
+		{"''", `''\'''\'''`},
+		{"line 1\nline 2", "'line 1\nline 2'"},
+		{`$HOME "x" \n *`, `'$HOME "x" \n *'`},
+		{"", "''"},
+	}
+	sh, _ := exec.LookPath("sh")
+	for _, tt := range tests {
+		got := shellQuote(tt.in)
+		if got != tt.want {
+			t.Errorf("shellQuote(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+		if sh == "" {
+			continue
+		}
+		// The shell must read the word back as the original text.
+		out, err := exec.Command(sh, "-c", "printf %s "+got).Output()
+		if err != nil || string(out) != tt.in {
+			t.Errorf("sh read %s as %q (%v), want %q", got, out, err, tt.in)
+		}
+	}
+}
+
+func TestShellQuoteLines(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"a.txt\nmy file.txt\n", "'a.txt' 'my file.txt'"},
+		{"it's\r\nfine\r\n", `'it'\''s' 'fine'`},
+		{"one\n\n  \ntwo", "'one' 'two'"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := shellQuoteLines(tt.in); got != tt.want {
+			t.Errorf("shellQuoteLines(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+	"unsafe"
+)
+
+// TestOSC52WithoutTerminal runs itself in a new session, which has no
+// controlling terminal, so opening /dev/tty fails as it does under cron.
+// With stderr a file and no SSH_TTY, OSC 52 must report errNoTTY.
+func TestOSC52WithoutTerminal(t *testing.T) {
+	if os.Getenv("GOCLIP_TEST_NO_TTY") == "1" {
+		err := writeClipboardOSC52("x", clipOptions{})
+		if !errors.Is(err, errNoTTY) {
+			t.Fatalf("err = %v, want errNoTTY", err)
+		}
+		_, err = writeToClipboardMethod("x", clipOptions{helper: methodOSC52})
+		if err == nil || !strings.Contains(err.Error(), "requires a terminal") {
+			t.Fatalf("writeToClipboardMethod err = %v", err)
+		}
+		return
+	}
+	stderr, err := os.CreateTemp(t.TempDir(), "stderr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stderr.Close()
+	cmd := exec.Command(os.Args[0], "-test.run=^TestOSC52WithoutTerminal$", "-test.v")
+	cmd.Env = append(os.Environ(), "GOCLIP_TEST_NO_TTY=1", "SSH_TTY=")
+	cmd.Stderr = stderr
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("subprocess: %v\n%s", err, out)
+	}
+	if !strings.Contains(string(out), "--- PASS") {
+		t.Fatalf("subprocess didn't run the test:\n%s", out)
+	}
+	if b, _ := os.ReadFile(stderr.Name()); strings.Contains(string(b), "\x1b]52") {
+		t.Errorf("OSC 52 was written to a non-terminal stderr")
+	}
+}
+
+// openPTY opens a new pseudo-terminal and returns its master side and the
+// path of its slave side.
+func openPTY(t *testing.T) (*os.File, string) {
+	t.Helper()
+	fd, err := syscall.Open("/dev/ptmx", syscall.O_RDWR|syscall.O_NOCTTY|syscall.O_NONBLOCK|syscall.O_CLOEXEC, 0)
+	if err != nil {
+		t.Skip("no pseudo-terminals:", err)
+	}
+	// Non-blocking, so reads can time out.
+	m := os.NewFile(uintptr(fd), "/dev/ptmx")
+	t.Cleanup(func() { m.Close() })
+	var unlock int32
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, m.Fd(), syscall.TIOCSPTLCK, uintptr(unsafe.Pointer(&unlock))); errno != 0 {
+		t.Skip("unlock pty:", errno)
+	}
+	var n uint32
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, m.Fd(), syscall.TIOCGPTN, uintptr(unsafe.Pointer(&n))); errno != 0 {
+		t.Skip("pty number:", errno)
+	}
+	return m, fmt.Sprintf("/dev/pts/%d", n)
+}
+
+// TestOSC52SSHTTY runs itself with a pseudo-terminal as its controlling
+// terminal, so /dev/tty works, and checks the sequence also goes to
+// $SSH_TTY when that is a different terminal, and only once when it is
+// the same one.
+func TestOSC52SSHTTY(t *testing.T) {
+	if os.Getenv("GOCLIP_TEST_SSH_TTY") == "1" {
+		if err := writeClipboardOSC52("x", clipOptions{}); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	const seq = "\x1b]52;c;eA==\x07"
+	tests := []struct {
+		name      string
+		sshTTY    string // "pty" for the controlling terminal itself
+		wantTTY   int
+		wantOther int
+	}{
+		{"no SSH_TTY", "", 1, 0},
+		{"different SSH_TTY", "other", 1, 1},
+		{"SSH_TTY is the terminal", "pty", 1, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, slavePath := openPTY(t)
+			slave, err := os.OpenFile(slavePath, os.O_RDWR|syscall.O_NOCTTY, 0)
+			if err != nil {
+				t.Skip("open pty:", err)
+			}
+			defer slave.Close()
+			other := filepath.Join(t.TempDir(), "ssh-tty")
+			if err := os.WriteFile(other, nil, 0o600); err != nil {
+				t.Fatal(err)
+			}
+			sshTTY := map[string]string{"": "", "other": other, "pty": slavePath}[tt.sshTTY]
+
+			cmd := exec.Command(os.Args[0], "-test.run=^TestOSC52SSHTTY$")
+			cmd.Env = append(os.Environ(), "GOCLIP_TEST_SSH_TTY=1", "SSH_TTY="+sshTTY)
+			cmd.Stdin = slave
+			cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true, Setctty: true, Ctty: 0}
+			if out, err := cmd.CombinedOutput(); err != nil {
+				t.Fatalf("subprocess: %v\n%s", err, out)
+			}
+
+			var got []byte
+			buf := make([]byte, 256)
+			if err := m.SetReadDeadline(time.Now().Add(200 * time.Millisecond)); err != nil {
+				t.Fatal(err)
+			}
+			for {
+				n, err := m.Read(buf)
+				got = append(got, buf[:n]...)
+				if err != nil {
+					break
+				}
+			}
+			if n := strings.Count(string(got), seq); n != tt.wantTTY {
+				t.Errorf("terminal got %d sequences (%q), want %d", n, got, tt.wantTTY)
+			}
+			b, _ := os.ReadFile(other)
+			if n := strings.Count(string(b), seq); n != tt.wantOther {
+				t.Errorf("$SSH_TTY got %d sequences, want %d", n, tt.wantOther)
+			}
+		})
+	}
+}
+
+// TestOSC52TTYFIFO points --tty at a named pipe and reads the sequence
+// from the other end.
+func TestOSC52TTYFIFO(t *testing.T) {
+	fifo := filepath.Join(t.TempDir(), "fifo")
+	if err := syscall.Mkfifo(fifo, 0o600); err != nil {
+		t.Skip("no FIFOs:", err)
+	}
+	got := make(chan string, 1)
+	go func() {
+		b, _ := os.ReadFile(fifo) // blocks until the writer opens it
+		got <- string(b)
+	}()
+	if err := writeClipboardOSC52("piped", clipOptions{tty: fifo}); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case seq := <-got:
+		if want := osc52Sequence("piped", false); seq != want {
+			t.Errorf("read %q, want %q", seq, want)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("nothing read from the FIFO")
+	}
+}
@@ -0,0 +1,9 @@
+//go:build !linux && !windows && !darwin && !freebsd && !netbsd && !openbsd && !dragonfly
+
+package main
+
+// isTerminal has no way to check for a terminal on this platform and
+// assumes there is none.
+func isTerminal(fd uintptr) bool {
+	return false
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"os"
+	"testing"
+)
+
+func TestIsTerminalRejectsNonTerminals(t *testing.T) {
+	devNull, err := os.Open(os.DevNull)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer devNull.Close()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	defer w.Close()
+	file, err := os.CreateTemp(t.TempDir(), "in")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	for _, tt := range []struct {
+		name string
+		f    *os.File
+	}{
+		{"null device", devNull},
+		{"pipe", r},
+		{"regular file", file},
+	} {
+		if isTerminal(tt.f.Fd()) {
+			t.Errorf("%s: isTerminal = true", tt.name)
+		}
+	}
+}
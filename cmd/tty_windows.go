@@ -0,0 +1,9 @@
+package main
+
+import "syscall"
+
+// isTerminal reports whether fd is a console handle.
+func isTerminal(fd uintptr) bool {
+	var mode uint32
+	return syscall.GetConsoleMode(syscall.Handle(fd), &mode) == nil
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// watchPoll is how often --watch-file checks the file, and watchDebounce how
+// long it must stay unchanged before it is copied, so a generator writing in
+// several steps produces one copy. Tests shorten them.
+var (
+	watchPoll     = 250 * time.Millisecond
+	watchDebounce = 500 * time.Millisecond
+)
+
+// fileStamp identifies a version of a watched file.
+type fileStamp struct {
+	mod  time.Time
+	size int64
+}
+
+func statStamp(path string) (fileStamp, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return fileStamp{}, err
+	}
+	return fileStamp{fi.ModTime(), fi.Size()}, nil
+}
+
+// readLimited reads at most maxBufferSize bytes of path.
+func readLimited(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	b, err := io.ReadAll(io.LimitReader(f, maxBufferSize))
+	return string(b), err
+}
+
+// watchFile copies path with copyFn now and again whenever it changes. It
+// runs until stop is closed, or with a nil stop until the process is
+// interrupted. The file may briefly disappear, as when a generator replaces
+// it by renaming; it is picked up again once it is back. Content identical
+// to the last copy is not copied again.
+func watchFile(path string, copyFn func(string) error, status io.Writer, stop <-chan struct{}) error {
+	var last string
+	var copied bool
+	copyNow := func() {
+		content, err := readLimited(path)
+		if err != nil {
+			fmt.Fprintln(status, "watch:", err)
+			return
+		}
+		if copied && content == last {
+			return
+		}
+		if err := copyFn(content); err != nil {
+			fmt.Fprintln(status, "watch:", err)
+			return
+		}
+		last, copied = content, true
+	}
+
+	seen, err := statStamp(path)
+	if err != nil {
+		return err
+	}
+	copyNow()
+
+	tick := time.NewTicker(watchPoll)
+	defer tick.Stop()
+	var changedAt time.Time // zero when no change is pending
+	for {
+		var now time.Time
+		select {
+		case <-stop:
+			return nil
+		case now = <-tick.C:
+		}
+		st, err := statStamp(path)
+		if err != nil {
+			continue
+		}
+		if st != seen {
+			seen, changedAt = st, now
+			continue
+		}
+		if !changedAt.IsZero() && now.Sub(changedAt) >= watchDebounce {
+			changedAt = time.Time{}
+			copyNow()
+		}
+	}
+}
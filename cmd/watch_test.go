@@ -0,0 +1,90 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWatchFile(t *testing.T) {
+	defer func(poll, debounce time.Duration) { watchPoll, watchDebounce = poll, debounce }(watchPoll, watchDebounce)
+	// The debounce is generous so a busy machine stalling the writer
+	// between steps doesn't look like the file settling.
+	watchPoll, watchDebounce = 20*time.Millisecond, 150*time.Millisecond
+	write := func(path, s string) {
+		if err := os.WriteFile(path, []byte(s), 0o600); err != nil {
+			t.Error(err)
+		}
+	}
+	tests := []struct {
+		name   string
+		change func(path string)
+		want   []string
+	}{
+		{"unchanged", func(string) {}, []string{"v1"}},
+		{"modified", func(p string) { write(p, "version 2") }, []string{"v1", "version 2"}},
+		{"rapid writes debounced", func(p string) {
+			f, err := os.OpenFile(p, os.O_WRONLY|os.O_APPEND, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+			for _, s := range []string{"a", "b", "c", "d"} {
+				f.WriteString(s)
+				time.Sleep(watchPoll / 2)
+			}
+		}, []string{"v1", "v1abcd"}},
+		{"same content rewritten", func(p string) {
+			write(p, "v1")
+			os.Chtimes(p, time.Now(), time.Now().Add(time.Hour))
+		}, []string{"v1"}},
+		{"replaced by rename", func(p string) {
+			tmp := p + ".tmp"
+			write(tmp, "renamed")
+			os.Remove(p)
+			time.Sleep(2 * watchPoll)
+			os.Rename(tmp, p)
+		}, []string{"v1", "renamed"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "out.txt")
+			write(path, "v1")
+			var mu sync.Mutex
+			var copies []string
+			copyFn := func(s string) error {
+				mu.Lock()
+				defer mu.Unlock()
+				copies = append(copies, s)
+				return nil
+			}
+			stop := make(chan struct{})
+			done := make(chan error)
+			go func() { done <- watchFile(path, copyFn, io.Discard, stop) }()
+
+			time.Sleep(watchPoll)
+			tt.change(path)
+			time.Sleep(watchPoll + watchDebounce + 3*watchPoll)
+			close(stop)
+			if err := <-done; err != nil {
+				t.Fatal(err)
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if !slices.Equal(copies, tt.want) {
+				t.Errorf("copied %q, want %q", copies, tt.want)
+			}
+		})
+	}
+}
+
+func TestWatchFileMissing(t *testing.T) {
+	err := watchFile(filepath.Join(t.TempDir(), "missing"), func(string) error { return nil }, io.Discard, nil)
+	if !os.IsNotExist(err) {
+		t.Errorf("err = %v, want not exist", err)
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"testing"
+	"unicode/utf8"
+)
+
+func TestDisplayWidth(t *testing.T) {
+	tests := []struct {
+		in                  string
+		bytes, runes, width int
+	}{
+		{"hello", 5, 5, 5},
+		{"héllo", 6, 5, 5},
+		{"é", 3, 2, 1}, // e + combining acute
+		{"日本語", 9, 3, 6},
+		{"한국어", 9, 3, 6},
+		{"ｆｕｌｌ", 12, 4, 8}, // fullwidth Latin
+		{"🙂", 4, 1, 2},
+		{"👍🏽", 8, 2, 4},       // skin tone modifiers are wide too
+		{"👩‍💻", 11, 3, 4},     // ZWJ is zero width
+		{"✔️", 6, 2, 1},       // variation selector is zero width
+		{"a\tb", 3, 3, 2},     // control characters take no columns
+		{"\x1b[31m", 5, 5, 4}, // width counts what a terminal prints literally
+		{"", 0, 0, 0},
+	}
+	for _, tt := range tests {
+		if got := len(tt.in); got != tt.bytes {
+			t.Errorf("len(%q) = %d, want %d", tt.in, got, tt.bytes)
+		}
+		if got := utf8.RuneCountInString(tt.in); got != tt.runes {
+			t.Errorf("runes(%q) = %d, want %d", tt.in, got, tt.runes)
+		}
+		if got := displayWidth(tt.in); got != tt.width {
+			t.Errorf("displayWidth(%q) = %d, want %d", tt.in, got, tt.width)
+		}
+	}
+}
+
+func TestHeadTailWidth(t *testing.T) {
+	tests := []struct {
+		in         string
+		n          int
+		head, tail string
+	}{
+		{"abcdef", 3, "abc", "def"},
+		{"abc", 10, "abc", "abc"},
+		{"abc", 0, "", ""},
+		{"日本語", 4, "日本", "本語"},
+		{"日本語", 3, "日", "語"}, // a wide character never straddles the limit
+		{"a🙂b", 2, "a", "b"},
+		{"a🙂b", 3, "a🙂", "🙂b"},
+	}
+	for _, tt := range tests {
+		if got := headWidth(tt.in, tt.n); got != tt.head {
+			t.Errorf("headWidth(%q, %d) = %q, want %q", tt.in, tt.n, got, tt.head)
+		}
+		if got := tailWidth(tt.in, tt.n); got != tt.tail {
+			t.Errorf("tailWidth(%q, %d) = %q, want %q", tt.in, tt.n, got, tt.tail)
+		}
+	}
+}
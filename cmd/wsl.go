@@ -0,0 +1,51 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+	"unicode/utf16"
+)
+
+// wslClipPath is where clip.exe lives when the Windows drive is mounted at
+// the default location and Windows' PATH isn't imported into WSL.
+const wslClipPath = "/mnt/c/Windows/System32/clip.exe"
+
+// isWSL reports whether we are running under the Windows Subsystem for
+// Linux, based on the kernel version string.
+func isWSL() bool {
+	if os.Getenv("WSL_DISTRO_NAME") != "" {
+		return true
+	}
+	b, err := os.ReadFile("/proc/version")
+	return err == nil && isWSLKernel(string(b))
+}
+
+// isWSLKernel reports whether a /proc/version string is from a WSL kernel.
+func isWSLKernel(version string) bool {
+	v := strings.ToLower(version)
+	return strings.Contains(v, "microsoft") || strings.Contains(v, "wsl")
+}
+
+// findClipExe locates the Windows clip.exe helper from inside WSL.
+func findClipExe() (string, bool) {
+	if p, err := exec.LookPath("clip.exe"); err == nil {
+		return p, true
+	}
+	if _, err := os.Stat(wslClipPath); err == nil {
+		return wslClipPath, true
+	}
+	return "", false
+}
+
+// encodeUTF16LE converts s to UTF-16LE with a byte order mark, the only
+// input encoding clip.exe reliably treats as Unicode.
+func encodeUTF16LE(s string) string {
+	units := utf16.Encode([]rune(s))
+	b := make([]byte, 0, 2+2*len(units))
+	b = append(b, 0xFF, 0xFE)
+	for _, u := range units {
+		b = append(b, byte(u), byte(u>>8))
+	}
+	return string(b)
+}
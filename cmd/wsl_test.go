@@ -0,0 +1,138 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// catPath is cat from the original PATH, for fake helpers to use once
+// isolateClipboard has replaced it.
+var catPath, _ = exec.LookPath("cat")
+
+// fakeHelper installs an executable called name in dir that saves its
+// arguments to name.args and its input to name.in, then exits with status
+// exit.
+func fakeHelper(t *testing.T, dir, name string, exit int) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("fake helpers are shell scripts")
+	}
+	if catPath == "" {
+		t.Skip("no cat for fake helpers")
+	}
+	script := fmt.Sprintf("#!/bin/sh\necho \"$@\" > '%s.args'\n'%s' > '%s.in'\nexit %d\n",
+		filepath.Join(dir, name), catPath, filepath.Join(dir, name), exit)
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// helperInput returns what the fake helper name was given, or "" if it
+// didn't run.
+func helperInput(dir, name string) string {
+	b, _ := os.ReadFile(filepath.Join(dir, name+".in"))
+	return string(b)
+}
+
+// isolateClipboard points PATH at a fresh directory for fake helpers and
+// clears the display variables, so no real helper is found. An empty file
+// named tty in the directory can stand in for a terminal with --tty.
+func isolateClipboard(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "tty"), nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir)
+	for _, v := range []string{"DISPLAY", "WAYLAND_DISPLAY", "WSL_DISTRO_NAME", "SSH_TTY"} {
+		t.Setenv(v, "")
+		os.Unsetenv(v)
+	}
+	return dir
+}
+
+func TestIsWSLKernel(t *testing.T) {
+	tests := []struct {
+		version string
+		want    bool
+	}{
+		{"Linux version 5.15.90.1-microsoft-standard-WSL2 (gcc ...)", true},
+		{"Linux version 4.4.0-19041-Microsoft (Microsoft@Microsoft.com)", true},
+		{"Linux version 6.1.0-wsl-custom", true},
+		{"Linux version 6.8.0-45-generic (buildd@lcy02-amd64-075)", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := isWSLKernel(tt.version); got != tt.want {
+			t.Errorf("isWSLKernel(%q) = %v, want %v", tt.version, got, tt.want)
+		}
+	}
+}
+
+func TestEncodeUTF16LE(t *testing.T) {
+	if got, want := encodeUTF16LE("hé🙂"), "\xff\xfeh\x00\xe9\x00\x3d\xd8\x42\xde"; got != want {
+		t.Errorf("encodeUTF16LE = %q, want %q", got, want)
+	}
+}
+
+// TestWSLOrdering checks the order helpers are tried in under WSL:
+// wl-copy, then clip.exe, then OSC 52.
+func TestWSLOrdering(t *testing.T) {
+	tests := []struct {
+		name       string
+		wlCopy     int // exit status; -1 means not installed
+		clipExe    int
+		wantMethod string
+	}{
+		{"wl-copy works", 0, 0, "wl-copy"},
+		{"wl-copy fails, clip.exe works", 1, 0, "clip.exe"},
+		{"no wl-copy", -1, 0, "clip.exe"},
+		{"both fail", 1, 1, methodOSC52},
+		{"nothing installed", -1, -1, methodOSC52},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := isolateClipboard(t)
+			t.Setenv("WAYLAND_DISPLAY", "wayland-0")
+			t.Setenv("WSL_DISTRO_NAME", "Ubuntu")
+			if tt.wlCopy >= 0 {
+				fakeHelper(t, dir, "wl-copy", tt.wlCopy)
+			}
+			if tt.clipExe >= 0 {
+				fakeHelper(t, dir, "clip.exe", tt.clipExe)
+			}
+			if tt.clipExe < 0 {
+				if _, err := os.Stat(wslClipPath); err == nil {
+					t.Skip("a real clip.exe is mounted")
+				}
+			}
+			tty := filepath.Join(dir, "tty")
+			method, err := writeToClipboardMethod("hé", clipOptions{tty: tty})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if method != tt.wantMethod {
+				t.Errorf("method = %s, want %s", method, tt.wantMethod)
+			}
+			if tt.wlCopy >= 0 && helperInput(dir, "wl-copy") != "hé" {
+				t.Errorf("wl-copy got %q", helperInput(dir, "wl-copy"))
+			}
+			clipIn := helperInput(dir, "clip.exe")
+			switch {
+			case tt.wlCopy == 0 && clipIn != "":
+				t.Errorf("clip.exe ran after wl-copy succeeded")
+			case tt.wlCopy != 0 && tt.clipExe >= 0 && clipIn != encodeUTF16LE("hé"):
+				t.Errorf("clip.exe got %q, want UTF-16LE", clipIn)
+			}
+			seq, _ := os.ReadFile(tty)
+			if got := strings.Contains(string(seq), "\x1b]52;c;"); got != (tt.wantMethod == methodOSC52) {
+				t.Errorf("OSC 52 written = %v, want %v", got, !got)
+			}
+		})
+	}
+}